@@ -0,0 +1,82 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"errors"
+	"math/big"
+	"net/netip"
+
+	"github.com/projectdiscovery/blackrock"
+)
+
+const (
+	// shuffleBits is the size (in host bits) of the blocks shuffled
+	// at once. Bigger ranges (IPv6) are shuffled block by block.
+	shuffleBits = 32
+)
+
+var (
+	ErrCidrBadFormat = errors.New("malformed input CIDR")
+)
+
+// streamCidr calls fn for every IP address (IPv4 or IPv6) in the CIDR range,
+// without materialising the whole range. If shuffle is true, addresses
+// are visited in random order (using seed).
+// The iteration stops when fn returns false.
+func streamCidr(inputCidr string, shuffle bool, seed int64, fn func(ip string) bool) error {
+	prefix, err := netip.ParsePrefix(inputCidr)
+	if err != nil {
+		return ErrCidrBadFormat
+	}
+
+	prefix = prefix.Masked()
+
+	if !shuffle {
+		for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+			if !fn(addr.String()) {
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	blockBits := min(hostBits, shuffleBits)
+	blockSize := int64(1) << blockBits
+	blocks := new(big.Int).Lsh(big.NewInt(1), uint(hostBits-blockBits))
+	base := new(big.Int).SetBytes(prefix.Addr().AsSlice())
+	br := blackrock.New(blockSize, seed)
+
+	for block := big.NewInt(0); block.Cmp(blocks) < 0; block.Add(block, big.NewInt(1)) {
+		blockBase := new(big.Int).Lsh(block, uint(blockBits))
+		blockBase.Add(blockBase, base)
+
+		for i := int64(0); i < blockSize; i++ {
+			offset := new(big.Int).Add(blockBase, big.NewInt(br.Shuffle(i)))
+			if !fn(intToAddr(offset, prefix.Addr().Is4()).String()) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// intToAddr converts an integer to an IPv4 or IPv6 address.
+func intToAddr(i *big.Int, is4 bool) netip.Addr {
+	if is4 {
+		var b [4]byte
+
+		return netip.AddrFrom4([4]byte(i.FillBytes(b[:])))
+	}
+
+	var b [16]byte
+
+	return netip.AddrFrom16([16]byte(i.FillBytes(b[:])))
+}
@@ -0,0 +1,176 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+const (
+	dataURIScheme = "data:"
+	// DefaultDataURIMediaType is the media type of data URIs without one (RFC 2397).
+	DefaultDataURIMediaType = "text/plain"
+)
+
+// DataURI is a parsed data: URI (RFC 2397).
+type DataURI struct {
+	// MediaType is lowercase, without parameters.
+	MediaType string
+	Params    map[string]string
+	Base64    bool
+	Data      []byte
+}
+
+// isDataURI checks if s is a data: URI.
+func isDataURI(s string) bool {
+	return len(s) >= len(dataURIScheme) && strings.EqualFold(s[:len(dataURIScheme)], dataURIScheme)
+}
+
+// ParseDataURI parses a data: URI, base64 or percent-encoded:
+//
+//	data:[<mediatype>][;<parameter>=<value>][;base64],<data>
+func ParseDataURI(uri string) (DataURI, error) {
+	if !isDataURI(uri) {
+		return DataURI{}, ErrInvalidDataURI
+	}
+
+	header, payload, ok := strings.Cut(uri[len(dataURIScheme):], ",")
+	if !ok {
+		return DataURI{}, fmt.Errorf("%w: missing comma", ErrInvalidDataURI)
+	}
+
+	result := DataURI{MediaType: DefaultDataURIMediaType, Params: map[string]string{}}
+
+	header = strings.TrimSpace(header)
+	if h, ok := cutSuffixFold(header, ";base64"); ok {
+		header, result.Base64 = h, true
+	} else if strings.EqualFold(header, "base64") {
+		header, result.Base64 = "", true
+	}
+
+	if header != "" {
+		mediaType, params, err := parseDataURIHeader(header)
+		if err != nil {
+			return DataURI{}, fmt.Errorf("%w: %w", ErrInvalidDataURI, err)
+		}
+
+		result.MediaType, result.Params = mediaType, params
+	}
+
+	data := percentDecode(payload)
+
+	if !result.Base64 {
+		result.Data = data
+
+		return result, nil
+	}
+
+	decoded, err := decodeBase64(data)
+	if err != nil {
+		return DataURI{}, fmt.Errorf("%w: %w", ErrInvalidDataURI, err)
+	}
+
+	result.Data = decoded
+
+	return result, nil
+}
+
+// Identifier returns a short identifier of the data URI
+// to be reported instead of the whole URI.
+func (d DataURI) Identifier() string {
+	return fmt.Sprintf("%s%s;bytes=%d", dataURIScheme, d.MediaType, len(d.Data))
+}
+
+// parseDataURIHeader parses the media type and the parameters of a data URI.
+// Unlike mime.ParseMediaType it's lenient, as browsers are: parameters
+// without value (e.g. ;utf8) are ignored instead of rejected.
+func parseDataURIHeader(header string) (string, map[string]string, error) {
+	mediaType, rest, _ := strings.Cut(header, ";")
+
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" {
+		// Parameters without media type.
+		mediaType = DefaultDataURIMediaType
+	}
+
+	if _, _, err := mime.ParseMediaType(mediaType); err != nil {
+		return "", nil, err
+	}
+
+	params := map[string]string{}
+
+	for param := range strings.SplitSeq(rest, ";") {
+		key, value, ok := strings.Cut(param, "=")
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+
+		params[key] = value
+	}
+
+	return mediaType, params, nil
+}
+
+// cutSuffixFold is strings.CutSuffix, case insensitive.
+func cutSuffixFold(s, suffix string) (string, bool) {
+	if len(s) < len(suffix) || !strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+		return s, false
+	}
+
+	return s[:len(s)-len(suffix)], true
+}
+
+// percentDecode decodes the %XX sequences, leaving the malformed
+// ones as they are (like browsers do).
+func percentDecode(s string) []byte {
+	out := make([]byte, 0, len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if b, err := hex.DecodeString(s[i+1 : i+3]); err == nil {
+				out = append(out, b[0])
+				i += 2
+
+				continue
+			}
+		}
+
+		out = append(out, s[i])
+	}
+
+	return out
+}
+
+// decodeBase64 decodes standard or URL-safe base64, padded or not,
+// ignoring whitespace.
+func decodeBase64(data []byte) ([]byte, error) {
+	s := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			return -1
+		}
+
+		return r
+	}, string(data))
+
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -11,10 +11,19 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	_ "embed"
 
+	"github.com/edoardottt/favirecon/pkg/output"
 	"github.com/projectdiscovery/goflags"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	UnknownName = output.UnknownName
 )
 
 //nolint:gochecknoglobals
@@ -22,36 +31,149 @@ var (
 	//go:embed db.json
 	dbJSON string
 
-	db                 map[string]string
+	db                 Database
 	ErrHashNotFound    = errors.New("hash not found")
 	ErrHashNotMatching = errors.New("hash not matching hash provided")
+	ErrEmptyDatabase   = errors.New("empty database")
 )
 
+// Database maps favicon hashes to the products using them.
+type Database map[string]output.Signatures
+
+// Conflict describes a hash defined in more than one database.
+type Conflict struct {
+	Hash     string
+	Source   string
+	Previous output.Signatures
+	Current  output.Signatures
+}
+
 func init() {
 	if err := json.Unmarshal([]byte(dbJSON), &db); err != nil {
 		log.Fatal("error while unmarshaling db")
 	}
 }
 
-// CheckFavicon checks if faviconHash is present in the database. If hash (slice) is not empty,
-// it checks also if that faviconHash is one of the inputted hashes.
-// If faviconHash is not found, an error is returned.
-func CheckFavicon(faviconHash string, hash goflags.StringSlice, url ...string) (string, error) {
-	if k, ok := db[faviconHash]; ok {
+// DefaultDatabase returns a copy of the embedded database.
+func DefaultDatabase() Database {
+	d := make(Database, len(db))
+	for k, v := range db {
+		d[k] = v
+	}
+
+	return d
+}
+
+// LoadDatabase reads a JSON or YAML (.yaml, .yml) signature file.
+func LoadDatabase(path string) (Database, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		// YAML is converted to JSON to reuse the signature parsing logic.
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if content, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	var d Database
+	if err := json.Unmarshal(content, &d); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return d, nil
+}
+
+// LoadDatabases builds the database used for the scan.
+// The embedded database is loaded first (unless noDefault is true),
+// then the files are merged in the given order: when a hash is
+// defined more than once, the last definition wins and a conflict
+// is reported.
+func LoadDatabases(paths []string, noDefault bool) (Database, []Conflict, error) {
+	d := Database{}
+	if !noDefault {
+		d = DefaultDatabase()
+	}
+
+	conflicts := []Conflict{}
+
+	for _, path := range paths {
+		custom, err := LoadDatabase(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		conflicts = append(conflicts, d.Merge(custom, path)...)
+	}
+
+	if len(d) == 0 {
+		return nil, nil, ErrEmptyDatabase
+	}
+
+	return d, conflicts, nil
+}
+
+// Merge adds the entries of other to the database, overriding
+// the existing ones. It returns the overridden entries.
+func (d Database) Merge(other Database, source string) []Conflict {
+	conflicts := []Conflict{}
+
+	for hash, signatures := range other {
+		if previous, ok := d[hash]; ok {
+			conflicts = append(conflicts, Conflict{
+				Hash:     hash,
+				Source:   source,
+				Previous: previous,
+				Current:  signatures,
+			})
+		}
+
+		d[hash] = signatures
+	}
+
+	return conflicts
+}
+
+// CheckFavicon checks if one of the favicon hashes is present in the embedded
+// database. See Database.Check.
+func CheckFavicon(faviconHashes FaviconHashes, hash goflags.StringSlice, url ...string) (output.Signatures, error) {
+	return db.Check(faviconHashes, hash, url...)
+}
+
+// Check checks if one of the favicon hashes is present in the database
+// (murmur3 first, then MD5 and SHA-256) and returns the matching signatures.
+// If hash (slice) is not empty, it checks also if one of the favicon hashes
+// is one of the inputted hashes.
+// If no hash is found, an error is returned.
+func (d Database) Check(faviconHashes FaviconHashes, hash goflags.StringSlice, url ...string) (output.Signatures, error) {
+	for _, faviconHash := range faviconHashes.Values() {
+		k, ok := d[faviconHash]
+		if !ok {
+			continue
+		}
+
 		if len(hash) != 0 {
-			if contains(hash, faviconHash) {
+			if matchHashes(hash, faviconHashes) {
 				return k, nil
 			}
 
-			return "", fmt.Errorf("[%s] %s %w", faviconHash, url, ErrHashNotMatching)
+			return nil, fmt.Errorf("[%s] %s %w", faviconHash, url, ErrHashNotMatching)
 		}
 
 		return k, nil
 	}
 
 	if len(url) == 0 {
-		return "", fmt.Errorf("%w", ErrHashNotFound)
+		return nil, fmt.Errorf("%w", ErrHashNotFound)
 	}
 
-	return "", fmt.Errorf("[%s] %s %w", faviconHash, url, ErrHashNotFound)
+	return nil, fmt.Errorf("[%s] %s %w", faviconHashes.MMH3, url, ErrHashNotFound)
 }
@@ -0,0 +1,14 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+// Unexported functions used by the tests.
+//
+//nolint:gochecknoglobals
+var (
+	StreamCidr = streamCidr
+)
@@ -8,10 +8,15 @@ package favirecon
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/edoardottt/favirecon/pkg/input"
 	"github.com/edoardottt/favirecon/pkg/output"
@@ -21,23 +26,68 @@ import (
 )
 
 type Runner struct {
-	Input     chan string
-	Output    chan output.Found
-	Result    output.Result
+	Input    chan string
+	Output   chan output.Found
+	Result   output.Result
+	InWg     *sync.WaitGroup
+	OutWg    *sync.WaitGroup
+	Options  input.Options
+	OutMutex *sync.Mutex
+	Scanner  *Scanner
+	Ports    []int
+	Stats    *Stats
+	// Checkpoint is set during the scan if a resume file is used.
+	Checkpoint *Checkpoint
+	// UserAgent is the User-Agent header sent by the default fetcher.
+	//
+	// Deprecated: use NewScanner with WithUserAgent and set Scanner instead.
+	// It's still honored if changed before running the scan.
 	UserAgent string
-	InWg      *sync.WaitGroup
-	OutWg     *sync.WaitGroup
-	Options   input.Options
-	OutMutex  *sync.Mutex
 }
 
 // New takes as input the options and returns
-// a new runner.
+// a new runner. Unset options use the default values
+// (see input.Options.ApplyDefaults).
 func New(options *input.Options) Runner {
-	if options.FileOutput != "" {
-		_, err := os.Create(options.FileOutput)
+	opts := *options
+	opts.ApplyDefaults()
+	options = &opts
+
+	// When resuming, results are appended to the existing output.
+	if options.FileOutput != "" && (options.Resume == "" || !fileutil.FileExists(options.Resume)) {
+		file, err := os.Create(options.FileOutput)
 		if err != nil {
 			gologger.Error().Msgf("%s", err)
+		} else {
+			_ = file.Close()
+		}
+	}
+
+	database, conflicts, err := LoadDatabases(options.Databases, options.NoDefaultDB)
+	if err != nil {
+		gologger.Fatal().Msgf("%s", err)
+	}
+
+	for _, c := range conflicts {
+		gologger.Info().Msgf("Hash %s from %s overrides [%s] with [%s]", c.Hash, c.Source,
+			strings.Join(c.Previous.Names(), ", "), strings.Join(c.Current.Names(), ", "))
+	}
+
+	stats := &Stats{}
+
+	scanner, err := NewScanner(withOptions(*options), WithDatabase(database))
+	if err != nil {
+		gologger.Fatal().Msgf("%s", err)
+	}
+
+	scanner.stats = stats
+
+	var ports []int
+
+	if options.Ports != "" {
+		ports, err = ParsePorts(options.Ports)
+		if err != nil {
+			gologger.Fatal().Msgf("%s", err)
 		}
 	}
 
@@ -45,167 +95,263 @@ func New(options *input.Options) Runner {
 		Input:     make(chan string, options.Concurrency),
 		Output:    make(chan output.Found, options.Concurrency),
 		Result:    output.New(),
-		UserAgent: golazy.GenerateRandomUserAgent(),
 		InWg:      &sync.WaitGroup{},
 		OutWg:     &sync.WaitGroup{},
 		Options:   *options,
 		OutMutex:  &sync.Mutex{},
+		Scanner:   scanner,
+		Ports:     ports,
+		Stats:     stats,
+		UserAgent: scanner.userAgent,
 	}
 }
 
 // Run takes the input and executes all the tasks
 // specified in the options.
+// The scan is stopped gracefully on SIGINT/SIGTERM, a second
+// signal kills the process.
 func (r *Runner) Run() {
-	r.OutWg.Add(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	go pullOutput(r)
+	go func() {
+		<-ctx.Done()
+		// Restore the default behavior for the next signal.
+		stop()
+	}()
 
-	r.InWg.Add(1)
+	r.RunWithContext(ctx)
+}
+
+// RunWithContext is like Run, but the scan is stopped when ctx is done:
+// no new targets are scanned, in-flight requests are canceled and the
+// results already found are written before returning.
+func (r *Runner) RunWithContext(ctx context.Context) {
+	if f, ok := r.Scanner.fetcher.(*HTTPFetcher); ok && r.UserAgent != "" {
+		f.UserAgent = r.UserAgent
+	}
+
+	if r.Options.FileOutput != "" && r.Options.Output == nil {
+		file, err := os.OpenFile(r.Options.FileOutput, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			gologger.Fatal().Msg(err.Error())
+		}
+
+		defer func() {
+			if err := file.Close(); err != nil {
+				gologger.Error().Msgf("%s", err)
+			}
+		}()
+
+		r.Options.Output = file
+	}
+
+	if r.Options.Resume != "" {
+		checkpoint, err := OpenCheckpoint(r.Options.Resume)
+		if err != nil {
+			gologger.Fatal().Msg(err.Error())
+		}
+
+		if checkpoint.Len() != 0 {
+			gologger.Info().Msgf("Resuming scan, %d targets already completed", checkpoint.Len())
+		}
+
+		r.Checkpoint = checkpoint
+
+		defer closeCheckpoint(ctx, r)
+	}
+
+	r.OutWg.Add(1)
 
-	go execute(r)
+	go pullOutput(r)
 
 	r.InWg.Add(1)
 
-	go pushInput(r)
+	if len(r.Options.Files) != 0 {
+		go executeLocal(ctx, r)
+		go pushFiles(ctx, r)
+	} else {
+		go execute(ctx, r)
+		go pushInput(ctx, r)
+	}
 
+	// Producers are not waited: they stop as soon as the workers are gone
+	// (e.g. blocked reading stdin).
 	r.InWg.Wait()
 
 	close(r.Output)
 	r.OutWg.Wait()
+
+	summary(ctx, r)
 }
 
-func pushInput(r *Runner) {
-	defer r.InWg.Done()
+// summary prints what has been completed.
+func summary(ctx context.Context, r *Runner) {
+	status := "completed"
+	if ctx.Err() != nil {
+		status = "interrupted"
+	}
+
+	gologger.Info().Msgf("Scan %s: %d targets processed, %d favicons hashed, %d results",
+		status, r.Stats.Targets.Load(), r.Stats.Favicons.Load(), r.Stats.Results.Load())
+
+	if skipped := r.Stats.Skipped.Load(); skipped != 0 {
+		gologger.Info().Msgf("%d targets skipped, completed in a previous run", skipped)
+	}
+}
+
+// closeCheckpoint saves the resume file if the scan has been
+// interrupted, otherwise it is no longer needed and it is removed.
+func closeCheckpoint(ctx context.Context, r *Runner) {
+	if ctx.Err() != nil {
+		if err := r.Checkpoint.Close(); err != nil {
+			gologger.Error().Msgf("%s", err)
+
+			return
+		}
+
+		gologger.Info().Msgf("Resume file saved in %s, run the same command to resume the scan", r.Options.Resume)
+
+		return
+	}
+
+	if err := r.Checkpoint.Remove(); err != nil {
+		gologger.Error().Msgf("%s", err)
+	}
+}
+
+// complete marks value as completely processed.
+func complete(r *Runner, value string) {
+	r.Stats.Targets.Add(1)
+
+	if r.Checkpoint == nil {
+		return
+	}
+
+	if err := r.Checkpoint.Add(value); err != nil {
+		gologger.Error().Msgf("%s", err)
+	}
+}
+
+// skip checks if value has been completed in a previous run.
+func skip(r *Runner, value string) bool {
+	if r.Checkpoint == nil || !r.Checkpoint.Completed(value) {
+		return false
+	}
+
+	r.Stats.Skipped.Add(1)
+
+	return true
+}
+
+func pushInput(ctx context.Context, r *Runner) {
+	defer close(r.Input)
 
 	if fileutil.HasStdin() {
 		scanner := bufio.NewScanner(os.Stdin)
 		for scanner.Scan() {
-			if r.Options.Cidr {
-				ips, err := handleCidrInput(scanner.Text())
-				if err != nil {
-					gologger.Error().Msg(err.Error())
-				} else {
-					for _, ip := range ips {
-						r.Input <- ip
-					}
-				}
-			} else {
-				r.Input <- scanner.Text()
+			if !pushValue(ctx, r, scanner.Text()) {
+				return
 			}
 		}
 	}
 
 	if r.Options.FileInput != "" {
 		for _, line := range golazy.RemoveDuplicateValues(golazy.ReadFileLineByLine(r.Options.FileInput)) {
-			if r.Options.Cidr {
-				ips, err := handleCidrInput(line)
-				if err != nil {
-					gologger.Error().Msg(err.Error())
-				} else {
-					for _, ip := range ips {
-						r.Input <- ip
-					}
-				}
-			} else {
-				r.Input <- line
+			if !pushValue(ctx, r, line) {
+				return
 			}
 		}
 	}
 
 	if r.Options.Input != "" {
-		if r.Options.Cidr {
-			ips, err := handleCidrInput(r.Options.Input)
-			if err != nil {
-				gologger.Error().Msg(err.Error())
-			} else {
-				for _, ip := range ips {
-					r.Input <- ip
-				}
-			}
-		} else {
-			r.Input <- r.Options.Input
+		pushValue(ctx, r, r.Options.Input)
+	}
+}
+
+// pushValue sends an input value to the input channel,
+// expanding CIDR ranges and ports if requested.
+// It returns false if the context is done.
+func pushValue(ctx context.Context, r *Runner, value string) bool {
+	if !r.Options.Cidr {
+		return pushTarget(ctx, r, value)
+	}
+
+	err := streamCidr(value, r.Options.Shuffle, time.Now().UnixNano(), func(ip string) bool {
+		return pushTarget(ctx, r, ip)
+	})
+	if err != nil {
+		gologger.Error().Msg(err.Error())
+	}
+
+	return ctx.Err() == nil
+}
+
+// pushTarget sends a target to the input channel, once
+// for each port if -ports is used.
+// It returns false if the context is done.
+func pushTarget(ctx context.Context, r *Runner, target string) bool {
+	targets := []string{target}
+	if len(r.Ports) != 0 {
+		targets = ExpandPorts(target, r.Ports)
+	}
+
+	for _, t := range targets {
+		if skip(r, t) {
+			continue
+		}
+
+		select {
+		case r.Input <- t:
+		case <-ctx.Done():
+			return false
 		}
 	}
 
-	close(r.Input)
+	return true
 }
 
-/*
-Try /favicon.ico first. Most common and lightweight check.
-Accept it only if:
-- Status is 200.
-- Content-Type is an image.
-- Body length is > 0 (some sites return 200 but empty).
-If valid, hash and lookup. ✅ Done.
-
-Fallback to parsing <link rel="icon" ...> in the HTML <head>:
-- Fetch original input URL (not with /favicon.ico appended).
-- Parse the HTML.
-- Extract: rel=icon, rel=shortcut icon, rel=apple-touch-icon
-If href is:
-- A relative path → resolve against base URL.
-- A full URL → use as-is.
-- Data URL → decode and hash directly.
-*/
-func execute(r *Runner) {
-	defer r.InWg.Done()
+// nextInput returns the next input value, false if the input
+// channel is closed or the context is done.
+func nextInput(ctx context.Context, r *Runner) (string, bool) {
+	select {
+	case <-ctx.Done():
+		return "", false
+	case value, ok := <-r.Input:
+		return value, ok && ctx.Err() == nil
+	}
+}
 
-	rl := rateLimiter(r)
+// execute scans the targets received from the input channel.
+func execute(ctx context.Context, r *Runner) {
+	defer r.InWg.Done()
 
-	for i := 0; i < r.Options.Concurrency; i++ {
-		r.InWg.Add(1)
+	err := r.Scanner.Stream(ctx, r.Input, func(value string, results []Result, err error) {
+		// Interrupted targets are not completed: their partial results
+		// are dropped, the target is scanned again when resuming.
+		if ctx.Err() != nil {
+			gologger.Debug().Msgf("Scan of %s interrupted, %d results dropped: %s", value, len(results), err)
 
-		go func() {
-			defer r.InWg.Done()
+			return
+		}
 
-			client, err := customClient(&r.Options)
-			if err != nil {
-				gologger.Error().Msgf("%s", err)
+		for _, result := range results {
+			r.Output <- result
+		}
 
-				return
-			}
+		complete(r, value)
 
-			for value := range r.Input {
-				faviconURL, err := PrepareURL(value)
-				if err != nil {
-					gologger.Error().Msgf("%s", err)
-
-					continue
-				}
-
-				rl.Take()
-
-				found, result, err := getFavicon(faviconURL, r.UserAgent, client)
-				if err != nil {
-					if errors.Is(err, ErrFaviconNotFound) {
-						gologger.Debug().Msgf("%s for url %s", err.Error(), value)
-					} else {
-						gologger.Error().Msgf("%s", err)
-					}
-				}
-
-				if !found {
-					gologger.Debug().Msgf("Fallback to HTML parsing for %s", value)
-
-					faviconURL, result, err = extractFaviconFromHTML(value, r.UserAgent, client)
-					if err != nil {
-						gologger.Debug().Msgf("Favicon not found for %s: %s", value, err)
-						continue
-					}
-				}
-
-				foundDB, err := CheckFavicon(result, r.Options.Hash, faviconURL)
-				if err != nil {
-					if r.Options.Verbose {
-						gologger.Error().Msgf("%s", err)
-					}
-
-					continue
-				}
-
-				r.Output <- output.Found{URL: value, Name: foundDB, Hash: result}
-			}
-		}()
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrProbeFailed):
+			// Errors are expected while probing schemes.
+			gologger.Debug().Msgf("%s", err)
+		default:
+			gologger.Error().Msgf("%s", err)
+		}
+	})
+	if err != nil {
+		gologger.Debug().Msgf("Scan stopped: %s", err)
 	}
 }
 
@@ -213,7 +359,9 @@ func pullOutput(r *Runner) {
 	defer r.OutWg.Done()
 
 	for o := range r.Output {
-		if !r.Result.Printed(o.URL) {
+		// The hash tells apart data: URIs having the same identifier.
+		if !r.Result.Printed(o.URL + " " + o.FaviconURL + " " + o.Hash) {
+			r.Stats.Results.Add(1)
 			r.OutWg.Add(1)
 
 			go writeOutput(r.OutWg, r.OutMutex, &r.Options, o)
@@ -224,15 +372,6 @@ func pullOutput(r *Runner) {
 func writeOutput(wg *sync.WaitGroup, m *sync.Mutex, options *input.Options, o output.Found) {
 	defer wg.Done()
 
-	if options.FileOutput != "" && options.Output == nil {
-		file, err := os.OpenFile(options.FileOutput, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
-		if err != nil {
-			gologger.Fatal().Msg(err.Error())
-		}
-
-		options.Output = file
-	}
-
 	m.Lock()
 
 	out := o.Format()
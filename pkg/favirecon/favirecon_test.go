@@ -7,9 +7,32 @@ This repository is under MIT License https://github.com/edoardottt/favirecon/blo
 package favirecon_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/edoardottt/favirecon/pkg/favirecon"
+	"github.com/edoardottt/favirecon/pkg/input"
+	"github.com/edoardottt/favirecon/pkg/output"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,6 +61,42 @@ func TestGetFaviconHash(t *testing.T) {
 	}
 }
 
+func TestGetFaviconHashes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  favirecon.FaviconHashes
+	}{
+		{
+			name:  "Test #1",
+			input: []byte("test"),
+			want: favirecon.FaviconHashes{
+				MMH3:   "-1541278541",
+				MD5:    "098f6bcd4621d373cade4e832627b4f6",
+				SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := favirecon.GetFaviconHashes(tt.input)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetFaviconHashesMatchesGetFaviconHash(t *testing.T) {
+	// Cover base64 outputs shorter, equal and longer than a RFC2045 line.
+	for size := 0; size <= 300; size++ {
+		input := make([]byte, size)
+		for i := range input {
+			input[i] = byte(i * 7)
+		}
+
+		require.Equal(t, favirecon.GetFaviconHash(input), favirecon.GetFaviconHashes(input).MMH3, "size %d", size)
+	}
+}
+
 func TestPrepareURL(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -99,6 +158,12 @@ func TestPrepareURL(t *testing.T) {
 			want:  "http://edoardottt.com/test/favicon.ico",
 			err:   nil,
 		},
+		{
+			name:  "IPv6 address without protocol",
+			input: "2001:db8::1",
+			want:  "http://[2001:db8::1]/favicon.ico",
+			err:   nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -108,3 +173,2075 @@ func TestPrepareURL(t *testing.T) {
 		})
 	}
 }
+
+func TestProbeURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		strategy string
+		want     []string
+		err      error
+	}{
+		{
+			name:     "too short input URL",
+			input:    "a.b",
+			strategy: input.ProbeHTTPSHTTP,
+			want:     nil,
+			err:      favirecon.ErrMalformedURL,
+		},
+		{
+			name:     "URL with protocol",
+			input:    "http://edoardottt.com",
+			strategy: input.ProbeHTTPS,
+			want:     []string{"http://edoardottt.com"},
+			err:      nil,
+		},
+		{
+			name:     "URL without protocol, https first",
+			input:    "edoardottt.com",
+			strategy: input.ProbeHTTPSHTTP,
+			want:     []string{"https://edoardottt.com", "http://edoardottt.com"},
+			err:      nil,
+		},
+		{
+			name:     "URL without protocol, http first",
+			input:    "edoardottt.com/test",
+			strategy: input.ProbeHTTPHTTPS,
+			want:     []string{"http://edoardottt.com/test", "https://edoardottt.com/test"},
+			err:      nil,
+		},
+		{
+			name:     "URL without protocol, http only",
+			input:    "192.168.1.1",
+			strategy: input.ProbeHTTP,
+			want:     []string{"http://192.168.1.1"},
+			err:      nil,
+		},
+		{
+			name:     "IPv6 address without protocol",
+			input:    "2001:db8::1/test",
+			strategy: input.ProbeHTTPSHTTP,
+			want:     []string{"https://[2001:db8::1]/test", "http://[2001:db8::1]/test"},
+			err:      nil,
+		},
+		{
+			name:     "IPv6 address with zone",
+			input:    "fe80::1%eth0",
+			strategy: input.ProbeHTTP,
+			want:     []string{"http://[fe80::1%25eth0]"},
+			err:      nil,
+		},
+		{
+			name:     "IPv6 address with port",
+			input:    "[2001:db8::1]:8443",
+			strategy: input.ProbeHTTPS,
+			want:     []string{"https://[2001:db8::1]:8443"},
+			err:      nil,
+		},
+		{
+			name:     "unknown strategy",
+			input:    "edoardottt.com",
+			strategy: "ftp",
+			want:     nil,
+			err:      favirecon.ErrUnknownProbing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := favirecon.ProbeURLs(tt.input, tt.strategy)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStreamCidr(t *testing.T) {
+	subnet := make([]string, 0, 256)
+	for i := range 256 {
+		subnet = append(subnet, "10.0.0."+strconv.Itoa(i))
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+		err   error
+	}{
+		{
+			name:  "single address",
+			input: "192.168.1.1/32",
+			want:  []string{"192.168.1.1"},
+		},
+		{
+			name:  "IPv4 range",
+			input: "192.168.1.0/29",
+			want: []string{"192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3",
+				"192.168.1.4", "192.168.1.5", "192.168.1.6", "192.168.1.7"},
+		},
+		{
+			name:  "IPv6 range",
+			input: "2001:db8::/126",
+			want:  []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"},
+		},
+		{
+			name:  "host bits set",
+			input: "10.0.0.5/24",
+			want:  subnet,
+		},
+		{
+			name:  "malformed",
+			input: "10.0.0.5/33",
+			err:   favirecon.ErrCidrBadFormat,
+		},
+	}
+	for _, tt := range tests {
+		for _, shuffle := range []bool{false, true} {
+			t.Run(tt.name+" shuffle "+strconv.FormatBool(shuffle), func(t *testing.T) {
+				got := []string{}
+
+				err := favirecon.StreamCidr(tt.input, shuffle, 42, func(ip string) bool {
+					got = append(got, ip)
+
+					return true
+				})
+				if tt.err != nil {
+					require.ErrorIs(t, err, tt.err)
+
+					return
+				}
+
+				require.NoError(t, err)
+
+				if shuffle {
+					require.ElementsMatch(t, tt.want, got)
+				} else {
+					require.Equal(t, tt.want, got)
+				}
+			})
+		}
+	}
+
+	t.Run("early stop", func(t *testing.T) {
+		for _, shuffle := range []bool{false, true} {
+			calls := 0
+
+			err := favirecon.StreamCidr("10.0.0.0/8", shuffle, 42, func(string) bool {
+				calls++
+
+				return calls < 3
+			})
+			require.NoError(t, err)
+			require.Equal(t, 3, calls)
+		}
+	})
+}
+
+// recordingFetcher replays the responses and records the requested URLs.
+type recordingFetcher struct {
+	replayFetcher
+	mutex sync.Mutex
+	urls  []string
+}
+
+func (f *recordingFetcher) Fetch(ctx context.Context, url string) (*favirecon.Response, error) {
+	f.mutex.Lock()
+	f.urls = append(f.urls, url)
+	f.mutex.Unlock()
+
+	return f.replayFetcher.Fetch(ctx, url)
+}
+
+func TestRunCidrIPv6(t *testing.T) {
+	icon := string(pngIcon())
+	fetcher := &recordingFetcher{replayFetcher: replayFetcher{
+		"https://[2001:db8::]/favicon.ico":  icon,
+		"https://[2001:db8::1]/favicon.ico": icon,
+	}}
+
+	var buf bytes.Buffer
+
+	runner := favirecon.New(&input.Options{
+		Input:       "2001:db8::/127",
+		Cidr:        true,
+		Probe:       input.ProbeHTTPS,
+		Concurrency: 1,
+		Output:      &buf,
+	})
+
+	scanner, err := favirecon.NewScanner(favirecon.WithFetcher(fetcher), favirecon.WithAll(),
+		favirecon.WithProbe(input.ProbeHTTPS))
+	require.NoError(t, err)
+
+	runner.Scanner = scanner
+	runner.Run()
+
+	require.ElementsMatch(t, []string{"https://[2001:db8::]/favicon.ico", "https://[2001:db8::1]/favicon.ico"},
+		fetcher.urls)
+	require.Equal(t, 2, strings.Count(buf.String(), "[unknown]"))
+}
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []int
+		err   error
+	}{
+		{
+			name:  "list",
+			input: "80,443",
+			want:  []int{80, 443},
+			err:   nil,
+		},
+		{
+			name:  "range and duplicates",
+			input: "8080-8082,8081,80",
+			want:  []int{8080, 8081, 8082, 80},
+			err:   nil,
+		},
+		{
+			name:  "preset",
+			input: "top-web",
+			want:  favirecon.PortPresets["top-web"],
+			err:   nil,
+		},
+		{
+			name:  "out of range",
+			input: "80,70000",
+			want:  nil,
+			err:   favirecon.ErrInvalidPort,
+		},
+		{
+			name:  "inverted range",
+			input: "90-80",
+			want:  nil,
+			err:   favirecon.ErrInvalidPort,
+		},
+		{
+			name:  "empty",
+			input: ",",
+			want:  nil,
+			err:   favirecon.ErrInvalidPort,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := favirecon.ParsePorts(tt.input)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExpandPorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		ports []int
+		want  []string
+	}{
+		{
+			name:  "host without scheme",
+			input: "192.168.1.1",
+			ports: []int{80, 8443, 9999},
+			want:  []string{"http://192.168.1.1:80", "https://192.168.1.1:8443", "192.168.1.1:9999"},
+		},
+		{
+			name:  "IPv6 host",
+			input: "2001:db8::1",
+			ports: []int{8080},
+			want:  []string{"http://[2001:db8::1]:8080"},
+		},
+		{
+			name:  "host with path",
+			input: "edoardottt.com/app",
+			ports: []int{9999},
+			want:  []string{"edoardottt.com:9999/app"},
+		},
+		{
+			name:  "URL with scheme",
+			input: "https://edoardottt.com/app",
+			ports: []int{80, 8443},
+			want:  []string{"https://edoardottt.com:80/app", "https://edoardottt.com:8443/app"},
+		},
+		{
+			name:  "host with port",
+			input: "edoardottt.com:8080",
+			ports: []int{80},
+			want:  []string{"edoardottt.com:8080"},
+		},
+		{
+			name:  "URL with port",
+			input: "http://edoardottt.com:8080",
+			ports: []int{80},
+			want:  []string{"http://edoardottt.com:8080"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := favirecon.ExpandPorts(tt.input, tt.ports)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckFavicon(t *testing.T) {
+	tests := []struct {
+		name  string
+		input favirecon.FaviconHashes
+		want  output.Signatures
+		err   error
+	}{
+		{
+			name:  "legacy entry",
+			input: favirecon.FaviconHashes{MMH3: "-1000719429"},
+			want:  output.Signatures{{Name: "SpamSniper"}},
+			err:   nil,
+		},
+		{
+			name:  "structured entry",
+			input: favirecon.FaviconHashes{MMH3: "1015545776"},
+			want: output.Signatures{{
+				Name:      "pfSense",
+				Product:   "pfSense",
+				Vendor:    "Netgate",
+				Category:  "firewall",
+				Tags:      []string{"firewall", "router"},
+				CPE:       "cpe:2.3:a:netgate:pfsense:*:*:*:*:*:*:*:*",
+				Reference: "https://www.pfsense.org/",
+			}},
+			err: nil,
+		},
+		{
+			name:  "hash not found",
+			input: favirecon.FaviconHashes{MMH3: "1"},
+			want:  nil,
+			err:   favirecon.ErrHashNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := favirecon.CheckFavicon(tt.input, nil)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDatabaseMerge(t *testing.T) {
+	d := favirecon.Database{
+		"1": output.Signatures{{Name: "A"}},
+		"2": output.Signatures{{Name: "B"}},
+	}
+	custom := favirecon.Database{
+		"2": output.Signatures{{Name: "C"}},
+		"3": output.Signatures{{Name: "D"}},
+	}
+
+	conflicts := d.Merge(custom, "custom.json")
+
+	require.Equal(t, []favirecon.Conflict{{
+		Hash:     "2",
+		Source:   "custom.json",
+		Previous: output.Signatures{{Name: "B"}},
+		Current:  output.Signatures{{Name: "C"}},
+	}}, conflicts)
+	require.Equal(t, favirecon.Database{
+		"1": output.Signatures{{Name: "A"}},
+		"2": output.Signatures{{Name: "C"}},
+		"3": output.Signatures{{Name: "D"}},
+	}, d)
+}
+
+func TestLoadDatabase(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    favirecon.Database
+		err     bool
+	}{
+		{
+			name: "YAML",
+			file: "db.yaml",
+			content: `-1541278541: Example
+116323821:
+  - Spring Boot
+  - name: Spring Boot Admin
+    vendor: codecentric
+"md5:098f6bcd4621d373cade4e832627b4f6":
+  product: Grafana
+  vendor: Grafana Labs
+  category: monitoring
+  tags: [dashboard, observability]
+  cpe: cpe:2.3:a:grafana:grafana:*:*:*:*:*:*:*:*
+`,
+			want: favirecon.Database{
+				"-1541278541": output.Signatures{{Name: "Example"}},
+				"116323821":   output.Signatures{{Name: "Spring Boot"}, {Name: "Spring Boot Admin", Vendor: "codecentric"}},
+				"md5:098f6bcd4621d373cade4e832627b4f6": output.Signatures{{
+					Name:     "Grafana",
+					Product:  "Grafana",
+					Vendor:   "Grafana Labs",
+					Category: "monitoring",
+					Tags:     []string{"dashboard", "observability"},
+					CPE:      "cpe:2.3:a:grafana:grafana:*:*:*:*:*:*:*:*",
+				}},
+			},
+		},
+		{
+			name:    "YML extension",
+			file:    "db.YML",
+			content: "-1: Example\n",
+			want:    favirecon.Database{"-1": output.Signatures{{Name: "Example"}}},
+		},
+		{
+			name:    "JSON",
+			file:    "db.json",
+			content: `{"-1": "Example", "2": ["A", {"name": "B"}]}`,
+			want: favirecon.Database{
+				"-1": output.Signatures{{Name: "Example"}},
+				"2":  output.Signatures{{Name: "A"}, {Name: "B"}},
+			},
+		},
+		{
+			name:    "malformed YAML",
+			file:    "malformed.yaml",
+			content: "-1: [Example\n",
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			got, err := favirecon.LoadDatabase(path)
+			if tt.err {
+				require.ErrorContains(t, err, path)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadDatabases(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.json")
+	empty := filepath.Join(dir, "empty.json")
+
+	require.NoError(t, os.WriteFile(first, []byte("-1: A\n2: B\n"), 0o600))
+	require.NoError(t, os.WriteFile(second, []byte(`{"2": "C", "3": "D"}`), 0o600))
+	require.NoError(t, os.WriteFile(empty, []byte(`{}`), 0o600))
+
+	got, conflicts, err := favirecon.LoadDatabases([]string{first, second}, true)
+	require.NoError(t, err)
+	require.Equal(t, favirecon.Database{
+		"-1": output.Signatures{{Name: "A"}},
+		"2":  output.Signatures{{Name: "C"}},
+		"3":  output.Signatures{{Name: "D"}},
+	}, got)
+	require.Equal(t, []favirecon.Conflict{{
+		Hash:     "2",
+		Source:   second,
+		Previous: output.Signatures{{Name: "B"}},
+		Current:  output.Signatures{{Name: "C"}},
+	}}, conflicts)
+
+	// The files override the embedded database.
+	def := favirecon.DefaultDatabase()
+
+	var hash string
+	for hash = range def {
+		break
+	}
+
+	override := filepath.Join(dir, "override.yaml")
+	require.NoError(t, os.WriteFile(override, []byte(`"`+hash+`": Custom`), 0o600))
+
+	got, conflicts, err = favirecon.LoadDatabases([]string{override}, false)
+	require.NoError(t, err)
+	require.Len(t, got, len(def))
+	require.Equal(t, output.Signatures{{Name: "Custom"}}, got[hash])
+	require.Equal(t, []favirecon.Conflict{{
+		Hash:     hash,
+		Source:   override,
+		Previous: def[hash],
+		Current:  output.Signatures{{Name: "Custom"}},
+	}}, conflicts)
+
+	_, _, err = favirecon.LoadDatabases([]string{empty}, true)
+	require.ErrorIs(t, err, favirecon.ErrEmptyDatabase)
+
+	_, _, err = favirecon.LoadDatabases([]string{filepath.Join(dir, "missing.yaml")}, false)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestValidateFavicon(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		err         error
+	}{
+		{
+			name:        "ICO served as image",
+			contentType: "image/x-icon",
+			body:        []byte{0x00, 0x00, 0x01, 0x00, 0x01, 0x00},
+			err:         nil,
+		},
+		{
+			name:        "PNG served as octet-stream",
+			contentType: "application/octet-stream",
+			body:        pngIcon(),
+			err:         nil,
+		},
+		{
+			name:        "SVG with XML declaration",
+			contentType: "image/svg+xml; charset=utf-8",
+			body:        []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`),
+			err:         nil,
+		},
+		{
+			name:        "soft 404 HTML page",
+			contentType: "text/html; charset=utf-8",
+			body:        []byte("<html><body>Not found</body></html>"),
+			err:         favirecon.ErrInvalidFavicon,
+		},
+		{
+			name:        "unknown format served as image",
+			contentType: "image/png",
+			body:        []byte("blocked by WAF"),
+			err:         favirecon.ErrInvalidFavicon,
+		},
+		{
+			name:        "empty body",
+			contentType: "image/png",
+			body:        []byte{},
+			err:         favirecon.ErrEmptyBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := favirecon.ValidateFavicon(tt.contentType, tt.body)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+// pngIcon returns a minimal PNG favicon.
+func pngIcon() []byte {
+	return []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00}
+}
+
+// servePNG serves pngIcon.
+func servePNG(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(pngIcon())
+}
+
+// redirectTo redirects to location with the status code.
+func redirectTo(location string, code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, location, code)
+	}
+}
+
+// faviconServer serves the handlers by path and 404 for the other paths.
+// If handlers is nil, pngIcon is served on /favicon.ico.
+func faviconServer(t *testing.T, handlers map[string]http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	if handlers == nil {
+		handlers = map[string]http.HandlerFunc{"/favicon.ico": servePNG}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handler, ok := handlers[r.URL.Path]; ok {
+			handler(w, r)
+
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRunWithContext(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "favicon.ico"), []byte("test"), 0o600))
+
+	tests := []struct {
+		name     string
+		canceled bool
+		targets  int64
+		want     string
+	}{
+		{
+			name:     "completed",
+			canceled: false,
+			targets:  1,
+			want:     "[-1541278541] [unknown] " + filepath.Join(dir, "favicon.ico") + "\n",
+		},
+		{
+			name:     "canceled",
+			canceled: true,
+			targets:  0,
+			want:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			runner := favirecon.New(&input.Options{
+				Files:       []string{dir},
+				All:         true,
+				Concurrency: 1,
+				Output:      &buf,
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.canceled {
+				cancel()
+			}
+
+			runner.RunWithContext(ctx)
+			cancel()
+
+			require.Equal(t, tt.want, buf.String())
+			require.Equal(t, tt.targets, runner.Stats.Targets.Load())
+		})
+	}
+}
+
+func TestLocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "static", "icons"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "favicon.ico"), []byte("test"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "static", "icons", "icon.png"), []byte("icon"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "static", "empty.ico"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "static", "firmware.bin"), make([]byte, 2*favirecon.KB), 0o600))
+
+	var buf bytes.Buffer
+
+	runner := favirecon.New(&input.Options{
+		Files:       []string{dir},
+		All:         true,
+		Concurrency: 2,
+		MaxSize:     1,
+		Output:      &buf,
+	})
+
+	runner.RunWithContext(context.Background())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	slices.Sort(lines)
+
+	require.Equal(t, []string{
+		"[-1541278541] [unknown] " + filepath.Join(dir, "favicon.ico"),
+		"[" + favirecon.GetFaviconHashes([]byte("icon")).MMH3 + "] [unknown] " + filepath.Join(dir, "static", "icons", "icon.png"),
+	}, lines)
+	require.Equal(t, int64(2), runner.Stats.Targets.Load())
+}
+
+func TestNewDefaults(t *testing.T) {
+	var userAgent atomic.Value
+
+	server := faviconServer(t, map[string]http.HandlerFunc{
+		"/favicon.ico": func(w http.ResponseWriter, r *http.Request) {
+			userAgent.Store(r.UserAgent())
+			http.Redirect(w, r, "/static/favicon.ico", http.StatusFound)
+		},
+		"/static/favicon.ico": servePNG,
+	})
+
+	var buf bytes.Buffer
+
+	// Only the options available before the library API.
+	runner := favirecon.New(&input.Options{
+		Input:       strings.TrimPrefix(server.URL, "http://"),
+		Concurrency: 1,
+		Output:      &buf,
+	})
+
+	require.Equal(t, input.DefaultTimeout, runner.Options.Timeout)
+	require.NotEmpty(t, runner.UserAgent)
+
+	runner.UserAgent = "favirecon-test"
+	runner.RunWithContext(context.Background())
+
+	require.Equal(t, int64(1), runner.Stats.Targets.Load())
+	require.Equal(t, int64(1), runner.Stats.Favicons.Load())
+	require.Equal(t, "favirecon-test", userAgent.Load())
+}
+
+func TestRunAll(t *testing.T) {
+	server := faviconServer(t, nil)
+
+	tests := []struct {
+		name string
+		all  bool
+		want string
+	}{
+		{
+			name: "unknown favicons not reported",
+			all:  false,
+			want: "",
+		},
+		{
+			name: "unknown favicons reported with the favicon URL",
+			all:  true,
+			want: "[" + favirecon.GetFaviconHash(pngIcon()) + "] [unknown] " + server.URL + " [favicon " + server.URL + "/favicon.ico]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			runner := favirecon.New(&input.Options{
+				Input:       server.URL,
+				Concurrency: 1,
+				All:         tt.all,
+				Output:      &buf,
+			})
+
+			runner.RunWithContext(context.Background())
+
+			require.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestRunWithContextInFlight(t *testing.T) {
+	fast := faviconServer(t, nil)
+	started := make(chan struct{})
+
+	var once sync.Once
+
+	// /favicon.ico is found, then the scan hangs on the icon advertised by the page.
+	slow := faviconServer(t, map[string]http.HandlerFunc{
+		"/": func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`<html><head><link rel="icon" href="/slow.png"></head></html>`))
+		},
+		"/favicon.ico": servePNG,
+		"/slow.png": func(_ http.ResponseWriter, r *http.Request) {
+			once.Do(func() { close(started) })
+			<-r.Context().Done()
+		},
+	})
+
+	dir := t.TempDir()
+	list := filepath.Join(dir, "targets.txt")
+	resume := filepath.Join(dir, "scan.resume")
+	require.NoError(t, os.WriteFile(list, []byte(fast.URL+"\n"+slow.URL+"\n"), 0o600))
+
+	var buf bytes.Buffer
+
+	runner := favirecon.New(&input.Options{
+		FileInput:   list,
+		Concurrency: 2,
+		Timeout:     30,
+		All:         true,
+		AllIcons:    true,
+		Resume:      resume,
+		Output:      &buf,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		runner.RunWithContext(ctx)
+	}()
+
+	<-started
+	require.Eventually(t, func() bool { return runner.Stats.Targets.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "RunWithContext did not return after cancellation")
+	}
+
+	// Only the results of the completed target are written.
+	require.Equal(t, "["+favirecon.GetFaviconHash(pngIcon())+"] [unknown] "+fast.URL+" [favicon "+fast.URL+"/favicon.ico] [favicon.ico]\n", buf.String())
+	require.Equal(t, int64(1), runner.Stats.Targets.Load())
+
+	checkpoint, err := favirecon.OpenCheckpoint(resume)
+	require.NoError(t, err)
+	require.True(t, checkpoint.Completed(fast.URL))
+	require.False(t, checkpoint.Completed(slow.URL))
+	require.NoError(t, checkpoint.Remove())
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.resume")
+
+	checkpoint, err := favirecon.OpenCheckpoint(path)
+	require.NoError(t, err)
+	require.Equal(t, 0, checkpoint.Len())
+	require.NoError(t, checkpoint.Add("https://example.com"))
+	require.NoError(t, checkpoint.Add("http://example.org:8080"))
+	require.NoError(t, checkpoint.Close())
+
+	checkpoint, err = favirecon.OpenCheckpoint(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, checkpoint.Len())
+	require.True(t, checkpoint.Completed("https://example.com"))
+	require.True(t, checkpoint.Completed("http://example.org:8080"))
+	require.False(t, checkpoint.Completed("https://example.net"))
+	require.NoError(t, checkpoint.Remove())
+	require.NoFileExists(t, path)
+}
+
+func TestScanner(t *testing.T) {
+	hashes := favirecon.GetFaviconHashes(pngIcon())
+	server := faviconServer(t, nil)
+
+	tests := []struct {
+		name    string
+		options []favirecon.Option
+		want    []favirecon.Result
+	}{
+		{
+			name:    "not in database",
+			options: nil,
+			want:    []favirecon.Result{},
+		},
+		{
+			name:    "not in database, all",
+			options: []favirecon.Option{favirecon.WithAll()},
+			want: []favirecon.Result{{
+				URL:        server.URL,
+				Hash:       hashes.MMH3,
+				Name:       favirecon.UnknownName,
+				FaviconURL: server.URL + "/favicon.ico",
+				MD5:        hashes.MD5,
+				SHA256:     hashes.SHA256,
+				Scheme:     "http",
+			}},
+		},
+		{
+			name: "custom database",
+			options: []favirecon.Option{favirecon.WithDatabase(favirecon.Database{
+				hashes.MMH3: output.Signatures{{Name: "Test", Product: "Test", Vendor: "Example"}},
+			})},
+			want: []favirecon.Result{{
+				URL:        server.URL,
+				Hash:       hashes.MMH3,
+				Name:       "Test",
+				FaviconURL: server.URL + "/favicon.ico",
+				MD5:        hashes.MD5,
+				SHA256:     hashes.SHA256,
+				Product:    "Test",
+				Vendor:     "Example",
+				Scheme:     "http",
+				Matcher:    favirecon.HashMatcherName,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := favirecon.NewScanner(append(tt.options, favirecon.WithHTTPClient(server.Client()))...)
+			require.NoError(t, err)
+
+			got, err := scanner.Scan(context.Background(), server.URL)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		scanner, err := favirecon.NewScanner(favirecon.WithAll(), favirecon.WithHTTPClient(server.Client()))
+		require.NoError(t, err)
+
+		targets := make(chan string, 2)
+		targets <- server.URL
+		targets <- "http://127.0.0.1:1"
+		close(targets)
+
+		got := map[string]int{}
+		errs := map[string]error{}
+
+		err = scanner.Stream(context.Background(), targets, func(target string, results []favirecon.Result, err error) {
+			got[target] = len(results)
+			errs[target] = err
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{server.URL: 1, "http://127.0.0.1:1": 0}, got)
+		require.NoError(t, errs[server.URL])
+		require.Error(t, errs["http://127.0.0.1:1"])
+	})
+
+	t.Run("invalid option", func(t *testing.T) {
+		_, err := favirecon.NewScanner(favirecon.WithConcurrency(0))
+		require.ErrorIs(t, err, input.ErrNegativeValue)
+	})
+}
+
+// replayFetcher serves recorded responses.
+type replayFetcher map[string]string
+
+func (f replayFetcher) Fetch(_ context.Context, url string) (*favirecon.Response, error) {
+	body, ok := f[url]
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+
+	return &favirecon.Response{
+		URL:           url,
+		StatusCode:    status,
+		Header:        http.Header{},
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+func TestScannerWithFetcher(t *testing.T) {
+	icon := string(pngIcon())
+	hashes := favirecon.GetFaviconHashes([]byte(icon))
+
+	fetcher := replayFetcher{
+		"https://example.com":                 `<html><head><link rel="icon" href="/static/icon.png"></head></html>`,
+		"https://example.com/static/icon.png": icon,
+	}
+
+	scanner, err := favirecon.NewScanner(favirecon.WithFetcher(fetcher), favirecon.WithAll())
+	require.NoError(t, err)
+
+	got, err := scanner.Scan(context.Background(), "example.com")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "https://example.com/static/icon.png", got[0].FaviconURL)
+	require.Equal(t, hashes.MMH3, got[0].Hash)
+	require.Equal(t, "https", got[0].Scheme)
+}
+
+// errRefused is returned by failingFetcher.
+var errRefused = errors.New("connection refused")
+
+// failingFetcher replays the responses, failing the requests
+// to the URLs starting with the given prefix.
+type failingFetcher struct {
+	replayFetcher
+	prefix string
+}
+
+func (f failingFetcher) Fetch(ctx context.Context, url string) (*favirecon.Response, error) {
+	if strings.HasPrefix(url, f.prefix) {
+		return nil, errRefused
+	}
+
+	return f.replayFetcher.Fetch(ctx, url)
+}
+
+func TestScannerProbe(t *testing.T) {
+	icon := string(pngIcon())
+	both := replayFetcher{
+		"https://example.com/favicon.ico": icon,
+		"http://example.com/favicon.ico":  icon,
+	}
+
+	tests := []struct {
+		name    string
+		fetcher favirecon.Fetcher
+		probe   string
+		schemes []string
+		err     error
+	}{
+		{
+			name:    "https fails, http serves the icon",
+			fetcher: failingFetcher{replayFetcher: both, prefix: "https://"},
+			probe:   input.ProbeHTTPSHTTP,
+			schemes: []string{"http"},
+		},
+		{
+			name:    "https serves the icon, http not tried",
+			fetcher: both,
+			probe:   input.ProbeHTTPSHTTP,
+			schemes: []string{"https"},
+		},
+		{
+			name:    "both schemes",
+			fetcher: both,
+			probe:   input.ProbeBoth,
+			schemes: []string{"https", "http"},
+		},
+		{
+			name:    "both schemes, https fails",
+			fetcher: failingFetcher{replayFetcher: both, prefix: "https://"},
+			probe:   input.ProbeBoth,
+			schemes: []string{"http"},
+		},
+		{
+			name:    "all schemes fail",
+			fetcher: failingFetcher{replayFetcher: both, prefix: "http"},
+			probe:   input.ProbeHTTPSHTTP,
+			schemes: []string{},
+			err:     favirecon.ErrProbeFailed,
+		},
+		{
+			name:    "single scheme fails",
+			fetcher: failingFetcher{replayFetcher: both, prefix: "http"},
+			probe:   input.ProbeHTTPS,
+			schemes: []string{},
+			err:     errRefused,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := favirecon.NewScanner(favirecon.WithFetcher(tt.fetcher), favirecon.WithAll(),
+				favirecon.WithProbe(tt.probe))
+			require.NoError(t, err)
+
+			got, err := scanner.Scan(context.Background(), "example.com")
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				require.ErrorIs(t, err, errRefused)
+			} else {
+				require.NoError(t, err)
+			}
+
+			schemes := []string{}
+			for _, result := range got {
+				require.Equal(t, "example.com", result.URL)
+				schemes = append(schemes, result.Scheme)
+			}
+
+			require.Equal(t, tt.schemes, schemes)
+		})
+	}
+}
+
+func TestScannerRateLimitCanceled(t *testing.T) {
+	scanner, err := favirecon.NewScanner(favirecon.WithFetcher(replayFetcher{}),
+		favirecon.WithRateLimit(1), favirecon.WithConcurrency(10))
+	require.NoError(t, err)
+
+	targets := make(chan string)
+
+	go func() {
+		defer close(targets)
+
+		for i := range 20 {
+			targets <- "http://host" + strconv.Itoa(i) + ".example.com"
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = scanner.Stream(ctx, targets, func(string, []favirecon.Result, error) {})
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+// pathMatcher identifies favicons by URL path.
+type pathMatcher map[string]string
+
+func (m pathMatcher) Name() string {
+	return "path"
+}
+
+func (m pathMatcher) Match(_ context.Context, favicon favirecon.Favicon) (output.Signatures, error) {
+	for path, name := range m {
+		if strings.HasSuffix(favicon.URL, path) {
+			return output.Signatures{{Name: name}}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func TestScannerWithMatchers(t *testing.T) {
+	icon := string(pngIcon())
+	hashes := favirecon.GetFaviconHashes([]byte(icon))
+
+	fetcher := replayFetcher{
+		"https://example.com/favicon.ico": icon,
+	}
+
+	tests := []struct {
+		name     string
+		database favirecon.Database
+		matchers []favirecon.Matcher
+		wantName string
+		matcher  string
+	}{
+		{
+			name:     "additional matcher",
+			database: favirecon.Database{"0": output.Signatures{{Name: "Other"}}},
+			matchers: []favirecon.Matcher{pathMatcher{"/favicon.ico": "Example"}},
+			wantName: "Example",
+			matcher:  "path",
+		},
+		{
+			name:     "hash matcher first",
+			database: favirecon.Database{hashes.MD5: output.Signatures{{Name: "Test"}}},
+			matchers: []favirecon.Matcher{pathMatcher{"/favicon.ico": "Example"}},
+			wantName: "Test",
+			matcher:  favirecon.HashMatcherName,
+		},
+		{
+			name:     "no match",
+			database: favirecon.Database{"0": output.Signatures{{Name: "Other"}}},
+			matchers: []favirecon.Matcher{pathMatcher{"/icon.png": "Example"}},
+			wantName: favirecon.UnknownName,
+			matcher:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := favirecon.NewScanner(
+				favirecon.WithFetcher(fetcher),
+				favirecon.WithDatabase(tt.database),
+				favirecon.WithMatchers(tt.matchers...),
+				favirecon.WithAll(),
+			)
+			require.NoError(t, err)
+
+			got, err := scanner.Scan(context.Background(), "https://example.com")
+			require.NoError(t, err)
+			require.Len(t, got, 1)
+			require.Equal(t, tt.wantName, got[0].Name)
+			require.Equal(t, tt.matcher, got[0].Matcher)
+		})
+	}
+}
+
+// testImage returns a 32x32 image with a gradient and a circle.
+func testImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.NRGBA{R: uint8(x * 8), G: uint8(y * 8), B: 0x80, A: 0xff}
+			if (x-16)*(x-16)+(y-16)*(y-16) < 64 {
+				c = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	return img
+}
+
+// dibPixels returns the bottom-up pixel rows, 24 or 32 bits per pixel.
+func dibPixels(img *image.NRGBA, bitCount int) []byte {
+	var pixels []byte
+
+	for y := img.Rect.Dy() - 1; y >= 0; y-- {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			c := img.NRGBAAt(x, y)
+
+			pixels = append(pixels, c.B, c.G, c.R)
+			if bitCount == 32 {
+				pixels = append(pixels, c.A)
+			}
+		}
+	}
+
+	return pixels
+}
+
+// dibHeader returns a BITMAPINFOHEADER.
+func dibHeader(width, height, bitCount int) []byte {
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header[0:], 40)
+	binary.LittleEndian.PutUint32(header[4:], uint32(width))
+	binary.LittleEndian.PutUint32(header[8:], uint32(height))
+	binary.LittleEndian.PutUint16(header[12:], 1)
+	binary.LittleEndian.PutUint16(header[14:], uint16(bitCount))
+
+	return header
+}
+
+// icoFile returns an ICO file containing the frames.
+func icoFile(frames ...[]byte) []byte {
+	ico := []byte{0, 0, 1, 0, byte(len(frames)), 0}
+	offset := 6 + 16*len(frames)
+
+	for _, frame := range frames {
+		entry := make([]byte, 16)
+		entry[0], entry[1] = 32, 32
+		binary.LittleEndian.PutUint16(entry[4:], 1)
+		binary.LittleEndian.PutUint16(entry[6:], 32)
+		binary.LittleEndian.PutUint32(entry[8:], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(entry[12:], uint32(offset))
+		ico = append(ico, entry...)
+		offset += len(frame)
+	}
+
+	for _, frame := range frames {
+		ico = append(ico, frame...)
+	}
+
+	return ico
+}
+
+// pngHeader returns the signature and the IHDR chunk of a PNG image,
+// enough to read its size.
+func pngHeader(width, height int) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[8:], uint32(height))
+	ihdr[12], ihdr[13] = 8, 6 // 8 bits RGBA.
+
+	header := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 13}
+	header = append(header, ihdr...)
+
+	return binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestPerceptualHash(t *testing.T) {
+	img := testImage()
+
+	var pngBuf, jpegBuf bytes.Buffer
+	require.NoError(t, png.Encode(&pngBuf, img))
+	require.NoError(t, jpeg.Encode(&jpegBuf, img, &jpeg.Options{Quality: 75}))
+
+	bmp := append([]byte{'B', 'M', 0, 0, 0, 0, 0, 0, 0, 0, 54, 0, 0, 0}, dibHeader(32, 32, 24)...)
+	bmp = append(bmp, dibPixels(img, 24)...)
+
+	// 32 bits DIB with double height and an empty AND mask.
+	dib := append(dibHeader(32, 64, 32), dibPixels(img, 32)...)
+	dib = append(dib, make([]byte, 4*32)...)
+
+	want, err := favirecon.PerceptualHash(pngBuf.Bytes())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		body        []byte
+		maxDistance int
+		err         error
+	}{
+		{name: "JPEG re-encoding", body: jpegBuf.Bytes(), maxDistance: 4},
+		{name: "BMP", body: bmp, maxDistance: 0},
+		{name: "ICO with PNG frame", body: icoFile(pngBuf.Bytes()), maxDistance: 0},
+		{name: "ICO with DIB frame", body: icoFile(dib), maxDistance: 0},
+		{name: "SVG", body: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), err: favirecon.ErrUnsupportedImage},
+		{name: "truncated ICO", body: icoFile(dib)[:100], err: favirecon.ErrInvalidICO},
+		{name: "oversized PNG", body: pngHeader(favirecon.MaxImageDimension+1, 16), err: favirecon.ErrImageTooLarge},
+		{name: "ICO with oversized PNG frame", body: icoFile(pngHeader(20000, 20000)), err: favirecon.ErrImageTooLarge},
+		{name: "ICO with PNG frame not matching the directory", body: icoFile(pngHeader(64, 64)), err: favirecon.ErrInvalidICO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := favirecon.PerceptualHash(tt.body)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.LessOrEqual(t, hammingDistance(t, want, got), tt.maxDistance)
+		})
+	}
+}
+
+func hammingDistance(t *testing.T, a, b string) int {
+	t.Helper()
+
+	x, err := strconv.ParseUint(a, 16, 64)
+	require.NoError(t, err)
+
+	y, err := strconv.ParseUint(b, 16, 64)
+	require.NoError(t, err)
+
+	return bits.OnesCount64(x ^ y)
+}
+
+func TestScannerPerceptualMatch(t *testing.T) {
+	img := testImage()
+
+	var pngBuf, jpegBuf bytes.Buffer
+	require.NoError(t, png.Encode(&pngBuf, img))
+	require.NoError(t, jpeg.Encode(&jpegBuf, img, &jpeg.Options{Quality: 75}))
+
+	reference, err := favirecon.PerceptualHash(pngBuf.Bytes())
+	require.NoError(t, err)
+
+	database := favirecon.Database{
+		favirecon.PerceptualHashPrefix + reference: output.Signatures{{Name: "Example"}},
+	}
+	fetcher := replayFetcher{"https://example.com/favicon.ico": jpegBuf.String()}
+
+	scanner, err := favirecon.NewScanner(favirecon.WithFetcher(fetcher), favirecon.WithDatabase(database))
+	require.NoError(t, err)
+
+	got, err := scanner.Scan(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "Example", got[0].Name)
+	require.Equal(t, favirecon.PerceptualMatcherName, got[0].Matcher)
+	require.NotNil(t, got[0].Distance)
+	require.Contains(t, got[0].Format(), "[probably Example (distance ")
+
+	require.NotEmpty(t, got[0].PHash)
+
+	// Disabled.
+	scanner, err = favirecon.NewScanner(favirecon.WithFetcher(fetcher), favirecon.WithDatabase(database),
+		favirecon.WithPerceptualDistance(-1))
+	require.NoError(t, err)
+
+	got, err = scanner.Scan(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	// No perceptual hashes in the database: favicons are not decoded.
+	scanner, err = favirecon.NewScanner(favirecon.WithFetcher(fetcher), favirecon.WithAll())
+	require.NoError(t, err)
+
+	got, err = scanner.Scan(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Empty(t, got[0].PHash)
+}
+
+func TestScannerICOFrames(t *testing.T) {
+	img := testImage()
+
+	var pngBuf bytes.Buffer
+	require.NoError(t, png.Encode(&pngBuf, img))
+
+	dib := append(dibHeader(32, 64, 32), dibPixels(img, 32)...)
+	dib = append(dib, make([]byte, 4*32)...)
+
+	ico := icoFile(pngBuf.Bytes(), dib)
+	hashes := favirecon.GetFaviconHashes(ico)
+	require.Len(t, hashes.Frames, 2)
+
+	fetcher := replayFetcher{"https://example.com/favicon.ico": string(ico)}
+	frame := favirecon.GetFaviconHashes(dib)
+
+	tests := []struct {
+		name     string
+		database favirecon.Database
+		filter   []string
+		want     string
+		frame    string
+	}{
+		{
+			name:     "whole file",
+			database: favirecon.Database{hashes.MMH3: output.Signatures{{Name: "File"}}, frame.MD5: output.Signatures{{Name: "Frame"}}},
+			want:     "File",
+			frame:    "",
+		},
+		{
+			name:     "second frame",
+			database: favirecon.Database{frame.MD5: output.Signatures{{Name: "Frame"}}},
+			want:     "Frame",
+			frame:    "32x32 32bpp #2",
+		},
+		{
+			name:     "second frame, hash filter",
+			database: favirecon.Database{frame.MD5: output.Signatures{{Name: "Frame"}}},
+			filter:   []string{frame.SHA256},
+			want:     "Frame",
+			frame:    "32x32 32bpp #2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := favirecon.NewScanner(favirecon.WithFetcher(fetcher), favirecon.WithDatabase(tt.database),
+				favirecon.WithHashes(tt.filter...))
+			require.NoError(t, err)
+
+			got, err := scanner.Scan(context.Background(), "https://example.com")
+			require.NoError(t, err)
+			require.Len(t, got, 1)
+			require.Equal(t, tt.want, got[0].Name)
+			require.Equal(t, tt.frame, got[0].Frame)
+			require.Equal(t, hashes.MMH3, got[0].Hash)
+		})
+	}
+}
+
+func TestICOFramesLimits(t *testing.T) {
+	img := testImage()
+
+	var pngBuf bytes.Buffer
+	require.NoError(t, png.Encode(&pngBuf, img))
+
+	// Every entry points to the same frame.
+	ico := []byte{0, 0, 1, 0, favirecon.MaxICOFrames, 0}
+	for range favirecon.MaxICOFrames {
+		entry := make([]byte, 16)
+		entry[0], entry[1] = 32, 32
+		binary.LittleEndian.PutUint32(entry[8:], uint32(pngBuf.Len()))
+		binary.LittleEndian.PutUint32(entry[12:], 6+16*favirecon.MaxICOFrames)
+		ico = append(ico, entry...)
+	}
+
+	ico = append(ico, pngBuf.Bytes()...)
+
+	hashes := favirecon.GetFaviconHashes(ico)
+	require.Len(t, hashes.Frames, favirecon.MaxICOFrames)
+
+	for _, frame := range hashes.Frames {
+		require.Equal(t, hashes.Frames[0].Hashes, frame.Hashes)
+		require.NotEmpty(t, frame.Hashes.DHash)
+	}
+
+	// Too many frames.
+	frames := make([][]byte, favirecon.MaxICOFrames+1)
+	for i := range frames {
+		frames[i] = pngBuf.Bytes()
+	}
+
+	ico = icoFile(frames...)
+	require.Empty(t, favirecon.GetFaviconHashes(ico).Frames)
+
+	_, err := favirecon.PerceptualHash(ico)
+	require.ErrorIs(t, err, favirecon.ErrInvalidICO)
+}
+
+func TestCanonicalSVG(t *testing.T) {
+	want := `<svg height="16" viewBox="0 0 16 16" width="16" xmlns="http://www.w3.org/2000/svg">` +
+		`<title>Logo &amp; co</title><path d="M0 0 L16 16" fill="#000"></path></svg>`
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		err   error
+	}{
+		{
+			name:  "already canonical",
+			input: want,
+			want:  want,
+		},
+		{
+			name: "formatting, comments and XML declaration",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<!-- Generator: Example -->
+<svg xmlns="http://www.w3.org/2000/svg" width="16"   height="16" viewBox="0  0 16 16">
+  <title>
+    Logo &amp; co
+  </title>
+  <path fill="#000" d="M0 0
+    L16 16"/>
+</svg>
+`,
+			want: want,
+		},
+		{
+			name:  "not an SVG",
+			input: `<html><body></body></html>`,
+			err:   favirecon.ErrInvalidSVG,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := favirecon.CanonicalSVG([]byte(tt.input))
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestSVGHash(t *testing.T) {
+	// The example of the README.
+	want := "b7e77860d756b4f41218dbd8d868b8b39419aac8bf86f5f56834e8c041360414"
+
+	for _, svg := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 16"><circle cx="8" cy="8" r="8" fill="#e34c26"/></svg>`,
+		"<?xml version=\"1.0\"?>\n<!-- logo -->\n<svg viewBox=\"0 0 16 16\" xmlns=\"http://www.w3.org/2000/svg\">\n" +
+			"  <circle r=\"8\" cx=\"8\" cy=\"8\" fill=\"#e34c26\"></circle>\n</svg>\n",
+	} {
+		got, err := favirecon.SVGHash([]byte(svg))
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+}
+
+func TestScannerSVG(t *testing.T) {
+	reference, err := favirecon.SVGHash([]byte(`<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16"><circle r="8"/></svg>`))
+	require.NoError(t, err)
+
+	fetcher := replayFetcher{
+		"https://example.com":          `<html><head><link rel="icon" type="image/svg+xml" href="/icon.svg"></head></html>`,
+		"https://example.com/icon.svg": "<svg height='16' width='16' xmlns='http://www.w3.org/2000/svg'>\n  <circle r='8'></circle>\n</svg>\n",
+	}
+	database := favirecon.Database{favirecon.SVGHashPrefix + reference: output.Signatures{{Name: "Example"}}}
+
+	scanner, err := favirecon.NewScanner(favirecon.WithFetcher(fetcher), favirecon.WithDatabase(database))
+	require.NoError(t, err)
+
+	got, err := scanner.Scan(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "Example", got[0].Name)
+	require.Equal(t, favirecon.SVGMatcherName, got[0].Matcher)
+	require.Equal(t, reference, got[0].SVG)
+}
+
+func TestParseDataURI(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  favirecon.DataURI
+		err   error
+	}{
+		{
+			name:  "base64 PNG",
+			input: "data:image/png;base64,iVBORw0KGgo=",
+			want: favirecon.DataURI{
+				MediaType: "image/png",
+				Params:    map[string]string{},
+				Base64:    true,
+				Data:      []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'},
+			},
+		},
+		{
+			name:  "percent-encoded SVG with charset",
+			input: "data:image/svg+xml;charset=UTF-8,%3Csvg xmlns=%22http://www.w3.org/2000/svg%22%3E%3C/svg%3E",
+			want: favirecon.DataURI{
+				MediaType: "image/svg+xml",
+				Params:    map[string]string{"charset": "UTF-8"},
+				Data:      []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`),
+			},
+		},
+		{
+			name:  "percent-encoded SVG with valueless parameter",
+			input: "data:image/svg+xml;utf8,%3Csvg%3E%3C/svg%3E",
+			want: favirecon.DataURI{
+				MediaType: "image/svg+xml",
+				Params:    map[string]string{},
+				Data:      []byte(`<svg></svg>`),
+			},
+		},
+		{
+			name:  "percent-encoded SVG with lowercase charset",
+			input: "data:image/svg+xml;charset=utf-8,%3Csvg%3E%3C%2Fsvg%3E",
+			want: favirecon.DataURI{
+				MediaType: "image/svg+xml",
+				Params:    map[string]string{"charset": "utf-8"},
+				Data:      []byte(`<svg></svg>`),
+			},
+		},
+		{
+			name:  "base64 with valueless parameter and quoted value",
+			input: `data:Image/PNG;utf8;name="icon.png";base64,iVBORw0KGgo=`,
+			want: favirecon.DataURI{
+				MediaType: "image/png",
+				Params:    map[string]string{"name": "icon.png"},
+				Base64:    true,
+				Data:      []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'},
+			},
+		},
+		{
+			name:  "parameters without media type",
+			input: "data:;charset=utf-8,abc",
+			want: favirecon.DataURI{
+				MediaType: favirecon.DefaultDataURIMediaType,
+				Params:    map[string]string{"charset": "utf-8"},
+				Data:      []byte("abc"),
+			},
+		},
+		{
+			name:  "uppercase scheme, unpadded base64 with whitespace",
+			input: "DATA:image/x-icon;BASE64,AAAB\n AA",
+			want: favirecon.DataURI{
+				MediaType: "image/x-icon",
+				Params:    map[string]string{},
+				Base64:    true,
+				Data:      []byte{0x00, 0x00, 0x01, 0x00},
+			},
+		},
+		{
+			name:  "no media type",
+			input: "data:,100%25 text 50%",
+			want: favirecon.DataURI{
+				MediaType: favirecon.DefaultDataURIMediaType,
+				Params:    map[string]string{},
+				Data:      []byte("100% text 50%"),
+			},
+		},
+		{
+			name:  "missing comma",
+			input: "data:image/png;base64",
+			err:   favirecon.ErrInvalidDataURI,
+		},
+		{
+			name:  "invalid base64",
+			input: "data:image/png;base64,!!!",
+			err:   favirecon.ErrInvalidDataURI,
+		},
+		{
+			name:  "invalid media type",
+			input: "data:image/png/x;utf8,abc",
+			err:   favirecon.ErrInvalidDataURI,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := favirecon.ParseDataURI(tt.input)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScannerDataURI(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg"><circle r="8"/></svg>`
+	hashes := favirecon.GetFaviconHashes([]byte(svg))
+
+	fetcher := replayFetcher{
+		"https://example.com": `<html><head><link rel="icon" href="data:image/svg+xml;charset=utf-8,%3Csvg xmlns=%22http://www.w3.org/2000/svg%22%3E%3Ccircle r=%228%22/%3E%3C/svg%3E"></head></html>`,
+	}
+
+	scanner, err := favirecon.NewScanner(favirecon.WithFetcher(fetcher), favirecon.WithAll())
+	require.NoError(t, err)
+
+	got, err := scanner.Scan(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "data:image/svg+xml;bytes="+strconv.Itoa(len(svg)), got[0].FaviconURL)
+	require.Equal(t, "image/svg+xml", got[0].MediaType)
+	require.Equal(t, hashes.MMH3, got[0].Hash)
+}
+
+func TestScannerBaseURL(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{
+			name: "relative to the URL after redirects",
+			page: `<html><head><link rel="icon" href="icon.png"></head></html>`,
+			want: "/app/icon.png",
+		},
+		{
+			name: "relative base href",
+			page: `<html><head><base href="/panel/"><link rel="icon" href="static/icon.png"></head></html>`,
+			want: "/panel/static/icon.png",
+		},
+		{
+			name: "base href relative to the URL after redirects",
+			page: `<html><head><base href="static/"><link rel="icon" href="icon.png"></head></html>`,
+			want: "/app/static/icon.png",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := faviconServer(t, map[string]http.HandlerFunc{
+				"/": redirectTo("/app/login", http.StatusFound),
+				"/app/login": func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write([]byte(tt.page))
+				},
+				tt.want: servePNG,
+			})
+
+			scanner, err := favirecon.NewScanner(favirecon.WithHTTPClient(server.Client()), favirecon.WithAll())
+			require.NoError(t, err)
+
+			got, err := scanner.Scan(context.Background(), server.URL)
+			require.NoError(t, err)
+			require.Len(t, got, 1)
+			require.Equal(t, server.URL+tt.want, got[0].FaviconURL)
+		})
+	}
+}
+
+func TestScannerHTMLRedirects(t *testing.T) {
+	login := `<html><head><link rel="icon" href="/static/icon.png"></head></html>`
+
+	tests := []struct {
+		name  string
+		pages map[string]string
+		want  int
+	}{
+		{
+			name: "meta refresh",
+			pages: map[string]string{
+				"/":      `<html><head><meta http-equiv="Refresh" content="0; URL='login'"></head></html>`,
+				"/login": login,
+			},
+			want: 1,
+		},
+		{
+			name: "javascript location assignment",
+			pages: map[string]string{
+				"/":      `<html><script>window.location.href = "/login";</script></html>`,
+				"/login": login,
+			},
+			want: 1,
+		},
+		{
+			name: "javascript location replace",
+			pages: map[string]string{
+				"/":      `<html><script>location.replace('/login')</script></html>`,
+				"/login": login,
+			},
+			want: 1,
+		},
+		{
+			name: "redirect loop",
+			pages: map[string]string{
+				"/":  `<meta http-equiv="refresh" content="0;url=/a">`,
+				"/a": `<meta http-equiv="refresh" content="0;url=/">`,
+			},
+			want: 0,
+		},
+		{
+			name: "too many redirects",
+			pages: map[string]string{
+				"/":      `<meta http-equiv="refresh" content="0;url=/1">`,
+				"/1":     `<meta http-equiv="refresh" content="0;url=/2">`,
+				"/2":     `<meta http-equiv="refresh" content="0;url=/3">`,
+				"/3":     `<meta http-equiv="refresh" content="0;url=/login">`,
+				"/login": login,
+			},
+			want: 0,
+		},
+		{
+			name: "javascript URL",
+			pages: map[string]string{
+				"/": `<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`,
+			},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := map[string]http.HandlerFunc{"/static/icon.png": servePNG}
+			for path, page := range tt.pages {
+				handlers[path] = func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write([]byte(page))
+				}
+			}
+
+			server := faviconServer(t, handlers)
+
+			scanner, err := favirecon.NewScanner(favirecon.WithHTTPClient(server.Client()), favirecon.WithAll())
+			require.NoError(t, err)
+
+			got, err := scanner.Scan(context.Background(), server.URL)
+			require.NoError(t, err)
+			require.Len(t, got, tt.want)
+
+			if tt.want != 0 {
+				require.Equal(t, server.URL+"/static/icon.png", got[0].FaviconURL)
+			}
+		})
+	}
+}
+
+func TestScannerRedirects(t *testing.T) {
+	cdn := faviconServer(t, map[string]http.HandlerFunc{"/icon.png": servePNG})
+
+	// Same address, another host name.
+	cdnURL := strings.Replace(cdn.URL, "127.0.0.1", "localhost", 1) + "/icon.png"
+
+	server := faviconServer(t, map[string]http.HandlerFunc{
+		"/favicon.ico":        redirectTo("/static/favicon.ico", http.StatusFound),
+		"/static/favicon.ico": redirectTo(cdnURL, http.StatusFound),
+		"/local/favicon.ico":  redirectTo("/static/icon.png", http.StatusMovedPermanently),
+		"/static/icon.png":    servePNG,
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		opts   []favirecon.Option
+		want   []string
+	}{
+		{
+			name:   "redirects to another host",
+			target: server.URL,
+			want:   []string{server.URL + "/favicon.ico", server.URL + "/static/favicon.ico", cdnURL},
+		},
+		{
+			name:   "no redirects",
+			target: server.URL,
+			opts:   []favirecon.Option{favirecon.WithNoRedirects()},
+		},
+		{
+			name:   "too many redirects",
+			target: server.URL,
+			opts:   []favirecon.Option{favirecon.WithMaxRedirects(1)},
+		},
+		{
+			name:   "redirect to another host not followed",
+			target: server.URL,
+			opts:   []favirecon.Option{favirecon.WithSameHostRedirects()},
+		},
+		{
+			name:   "redirect to the same host",
+			target: server.URL + "/local/",
+			opts:   []favirecon.Option{favirecon.WithSameHostRedirects(), favirecon.WithMaxRedirects(1)},
+			want:   []string{server.URL + "/local/favicon.ico", server.URL + "/static/icon.png"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := favirecon.NewScanner(append(tt.opts, favirecon.WithAll())...)
+			require.NoError(t, err)
+
+			got, err := scanner.Scan(context.Background(), tt.target)
+			require.NoError(t, err)
+
+			if tt.want == nil {
+				require.Empty(t, got)
+
+				return
+			}
+
+			require.Len(t, got, 1)
+			require.Equal(t, tt.want[0], got[0].FaviconURL)
+			require.Equal(t, tt.want[len(tt.want)-1], got[0].FinalURL)
+			require.Equal(t, tt.want, got[0].RedirectChain)
+		})
+	}
+}
+
+// iconServer serves the pages and an icon for every path under /static/
+// or ending with .ico/.png (different for each path), it records the
+// requested paths.
+func iconServer(t *testing.T, pages map[string]string) (*httptest.Server, *sync.Map) {
+	t.Helper()
+
+	requests := &sync.Map{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Store(r.URL.Path, struct{}{})
+
+		if page, ok := pages[r.URL.Path]; ok {
+			_, _ = w.Write([]byte(page))
+
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, ".ico") || strings.HasSuffix(r.URL.Path, ".png") {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(append(pngIcon(), r.URL.Path...))
+
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+// iconSources returns the favicon URL (without the server URL)
+// and the source of each result.
+func iconSources(serverURL string, results []favirecon.Result) map[string]string {
+	got := map[string]string{}
+	for _, result := range results {
+		got[strings.TrimPrefix(result.FaviconURL, serverURL)] = result.Source
+	}
+
+	return got
+}
+
+func TestScannerAllIcons(t *testing.T) {
+	server, _ := iconServer(t, map[string]string{
+		"/": `<html><head>
+<link rel="icon" href="/favicon.ico">
+<link rel="icon" sizes="32x32" href="/static/icon-32.png">
+<link rel="apple-touch-icon" href="static/apple.png">
+<meta name="msapplication-TileImage" content="/static/tile.png">
+</head></html>`,
+	})
+
+	scanner, err := favirecon.NewScanner(favirecon.WithAll(), favirecon.WithAllIcons())
+	require.NoError(t, err)
+
+	got, err := scanner.Scan(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	// /favicon.ico is reported once, even if advertised by the page.
+	require.Len(t, got, 4)
+	require.Equal(t, map[string]string{
+		"/favicon.ico":        favirecon.DefaultFaviconSource,
+		"/static/icon-32.png": "icon 32x32",
+		"/static/apple.png":   "apple-touch-icon",
+		"/static/tile.png":    "msapplication-TileImage",
+	}, iconSources(server.URL, got))
+}
+
+func TestScannerManifestIcons(t *testing.T) {
+	manifest := `{"icons": [{"src": "icons/192.png", "sizes": "192x192"}, {"src": "/static/512.png"}]}`
+	browserConfig := `<?xml version="1.0" encoding="utf-8"?>
+<browserconfig><msapplication><tile>
+<square150x150logo src="tile.png"/>
+</tile></msapplication></browserconfig>`
+
+	tests := []struct {
+		name     string
+		page     string
+		allIcons bool
+		want     map[string]string
+		skipped  []string
+	}{
+		{
+			name: "manifest and browserconfig",
+			page: `<link rel="manifest" href="/app/site.webmanifest">
+<meta name="msapplication-config" content="/app/browserconfig.xml">`,
+			allIcons: true,
+			want: map[string]string{
+				"/app/icons/192.png": "manifest 192x192",
+				"/static/512.png":    "manifest",
+				"/app/tile.png":      "browserconfig square150x150logo",
+			},
+		},
+		{
+			name:     "browserconfig disabled",
+			page:     `<meta name="msapplication-config" content="none">`,
+			allIcons: true,
+			want:     map[string]string{},
+			skipped:  []string{"/none", "/browserconfig.xml"},
+		},
+		{
+			name: "page without other icons",
+			page: `<link rel="manifest" href="/app/site.webmanifest">`,
+			want: map[string]string{"/app/icons/192.png": ""},
+		},
+		{
+			name: "page with other icons",
+			page: `<link rel="manifest" href="/app/site.webmanifest">
+<meta name="msapplication-config" content="/app/browserconfig.xml">
+<meta name="msapplication-TileImage" content="/static/tile.png">`,
+			want:    map[string]string{"/static/tile.png": ""},
+			skipped: []string{"/app/site.webmanifest", "/app/browserconfig.xml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := iconServer(t, map[string]string{
+				"/":                      "<html><head>" + tt.page + "</head></html>",
+				"/favicon.ico":           "",
+				"/app/site.webmanifest":  manifest,
+				"/app/browserconfig.xml": browserConfig,
+			})
+
+			opts := []favirecon.Option{favirecon.WithAll()}
+			if tt.allIcons {
+				opts = append(opts, favirecon.WithAllIcons())
+			}
+
+			scanner, err := favirecon.NewScanner(opts...)
+			require.NoError(t, err)
+
+			got, err := scanner.Scan(context.Background(), server.URL)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, iconSources(server.URL, got))
+
+			for _, path := range tt.skipped {
+				_, ok := requests.Load(path)
+				require.False(t, ok, path)
+			}
+		})
+	}
+}
+
+func TestScannerMaxSize(t *testing.T) {
+	icon := pngIcon()
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		err     string
+		reason  string
+	}{
+		{
+			name: "Content-Length over the limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Header().Set("Content-Length", strconv.Itoa(2*favirecon.KB))
+				_, _ = w.Write(icon)
+				w.(http.Flusher).Flush()
+
+				// The rest of the body never comes, the client must not wait for it.
+				<-r.Context().Done()
+			},
+			err:    favirecon.ErrFaviconTooLarge.Error(),
+			reason: favirecon.ErrFaviconTooLarge.Error() + ": 2048 bytes",
+		},
+		{
+			name: "chunked body over the limit",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				_, _ = w.Write(icon)
+
+				for range 4 {
+					_, _ = w.Write(make([]byte, favirecon.KB/2))
+					w.(http.Flusher).Flush()
+				}
+			},
+			err:    favirecon.ErrFaviconTooLarge.Error(),
+			reason: favirecon.ErrFaviconTooLarge.Error() + ": more than 1024 bytes",
+		},
+		{
+			name: "body under the limit",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				_, _ = w.Write(append(icon, make([]byte, favirecon.KB-len(icon))...))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := faviconServer(t, map[string]http.HandlerFunc{"/favicon.ico": tt.handler})
+
+			scanner, err := favirecon.NewScanner(favirecon.WithAll(), favirecon.WithMaxSize(1),
+				favirecon.WithTimeout(5*time.Second))
+			require.NoError(t, err)
+
+			start := time.Now()
+
+			got, err := scanner.Scan(context.Background(), server.URL)
+			require.NoError(t, err)
+			require.Less(t, time.Since(start), 2*time.Second)
+			require.Len(t, got, 1)
+			require.Equal(t, tt.err, got[0].Error)
+			require.Equal(t, tt.reason, got[0].Reason)
+
+			if tt.err != "" {
+				require.Equal(t, favirecon.UnknownName, got[0].Name)
+				require.Contains(t, got[0].Format(), "("+tt.reason+")")
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Fetcher retrieves the resources needed to identify a target:
+// favicons, HTML pages, Web App Manifests and browserconfig files.
+// Implementations must be safe for concurrent use.
+// HTTPFetcher is the default implementation, a custom one (a cache,
+// a recorded-response replayer, a headless browser...) can be used
+// with WithFetcher.
+type Fetcher interface {
+	// Fetch retrieves the resource at url. Non-2xx responses are not
+	// errors, the caller checks the status code. The caller closes
+	// the response body.
+	Fetch(ctx context.Context, url string) (*Response, error)
+}
+
+// Response is the response returned by a Fetcher.
+type Response struct {
+	// URL is the final URL of the resource, after redirects.
+	URL string
+	// Redirects contains the URLs requested to get the resource,
+	// from the first one to URL, nil if there were no redirects.
+	Redirects  []string
+	StatusCode int
+	Header     http.Header
+	// ContentLength is the length of the body, -1 if unknown.
+	ContentLength int64
+	Body          io.ReadCloser
+}
+
+// HTTPFetcher fetches the resources with an HTTP client.
+type HTTPFetcher struct {
+	Client    *http.Client
+	UserAgent string
+}
+
+// Fetch performs a GET request to url.
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.UserAgent != "" {
+		req.Header.Add("User-Agent", f.UserAgent)
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		URL:           resp.Request.URL.String(),
+		Redirects:     redirectChain(resp),
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		ContentLength: resp.ContentLength,
+		Body:          resp.Body,
+	}, nil
+}
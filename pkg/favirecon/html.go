@@ -7,12 +7,16 @@ This repository is under MIT License https://github.com/edoardottt/favirecon/blo
 package favirecon
 
 import (
-	"encoding/base64"
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/edoardottt/favirecon/pkg/input"
+	"github.com/projectdiscovery/gologger"
 )
 
 var (
@@ -22,73 +26,240 @@ var (
 	ErrInvalidDataURI         = errors.New("invalid data URI")
 )
 
-func extractFaviconFromHTML(pageURL, ua string, client *http.Client) (string, string, error) {
-	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+// iconLink is an icon advertised by an HTML page,
+// Href is absolute (or a data: URI).
+type iconLink struct {
+	Href   string
+	Source string
+}
+
+// extractFaviconFromHTML returns the first icon advertised by the HTML page.
+func extractFaviconFromHTML(ctx context.Context, pageURL string, fetcher Fetcher, options *input.Options) (Favicon, error) {
+	links, err := fetchIconLinks(ctx, pageURL, fetcher, options, false)
 	if err != nil {
-		return "", "", err
+		return Favicon{}, err
 	}
 
-	req.Header.Add("User-Agent", ua)
+	return fetchIcon(ctx, links[0], fetcher, options)
+}
 
-	resp, err := client.Do(req)
+// extractFaviconsFromHTML returns all the icons advertised by the HTML page.
+func extractFaviconsFromHTML(ctx context.Context, pageURL string, fetcher Fetcher, options *input.Options) ([]Favicon, error) {
+	links, err := fetchIconLinks(ctx, pageURL, fetcher, options, true)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+
+	favicons := []Favicon{}
+
+	for _, link := range links {
+		favicon, err := fetchIcon(ctx, link, fetcher, options)
+		if err != nil {
+			gologger.Debug().Msgf("Icon %s not retrieved for %s: %s", link.Href, pageURL, err)
+
+			if !isRejected(err) {
+				continue
+			}
+
+			favicon.Err = err
+		}
+
+		favicons = append(favicons, favicon)
+	}
+
+	return favicons, nil
+}
+
+// fetchIconLinks fetches the HTML page and returns all the
+// <link> tags having a rel containing "icon" and the msapplication-TileImage
+// meta tag. Icons declared in Web App Manifests (<link rel="manifest">)
+// and browserconfig.xml files (msapplication-config meta tag) are collected
+// only if all is true or if the page advertises no other icon.
+// If the page advertises no icon, meta refresh and JavaScript location
+// redirects are followed (at most MaxHTMLRedirects), according to the
+// redirect policy of the options.
+func fetchIconLinks(ctx context.Context, pageURL string, fetcher Fetcher, options *input.Options, all bool) ([]iconLink, error) {
+	visited := map[string]struct{}{}
+	start := pageURL
+
+	for redirects := 0; ; redirects++ {
+		visited[pageURL] = struct{}{}
+
+		doc, baseURL, err := fetchHTML(ctx, pageURL, fetcher)
+		if err != nil {
+			return nil, err
+		}
+
+		if links := pageIconLinks(ctx, doc, baseURL, fetcher, all); len(links) != 0 {
+			return links, nil
+		}
+
+		next := htmlRedirect(doc, baseURL)
+		if _, ok := visited[next]; next == "" || ok || redirects == MaxHTMLRedirects {
+			return nil, ErrFaviconLinkTagNotFound
+		}
+
+		if err := allowHTMLRedirect(options, start, next, redirects+1); err != nil {
+			gologger.Debug().Msgf("Not following HTML redirect from %s to %s: %s", pageURL, next, err)
+
+			return nil, ErrFaviconLinkTagNotFound
+		}
+
+		gologger.Debug().Msgf("Following HTML redirect from %s to %s", pageURL, next)
+
+		pageURL = next
+	}
+}
+
+// fetchHTML fetches and parses the HTML page. It returns the document
+// and the URL used to resolve its relative URLs.
+// Relative URLs are resolved against the page <base href>, if any,
+// and the page URL after redirects.
+func fetchHTML(ctx context.Context, pageURL string, fetcher Fetcher) (*goquery.Document, string, error) {
+	resp, err := fetcher.Fetch(ctx, pageURL)
+	if err != nil {
+		return nil, "", err
 	}
 
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", ErrHTMLNotFetched
+		return nil, "", ErrHTMLNotFetched
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, MaxHTMLSize))
 	if err != nil {
-		return "", "", err
+		return nil, "", err
 	}
 
-	var faviconHref string
+	return doc, pageBaseURL(doc, pageURL, resp.URL), nil
+}
+
+// pageIconLinks returns the icons advertised by the page (see fetchIconLinks).
+func pageIconLinks(ctx context.Context, doc *goquery.Document, baseURL string, fetcher Fetcher, all bool) []iconLink {
+	links := []iconLink{}
+	manifests := []string{}
 
-	doc.Find("link").EachWithBreak(func(i int, s *goquery.Selection) bool {
+	doc.Find("link").Each(func(i int, s *goquery.Selection) {
 		rel, _ := s.Attr("rel")
 		href, ok := s.Attr("href")
 
-		if ok && strings.Contains(strings.ToLower(rel), "icon") {
-			faviconHref = href
-			return false // break loop
+		rel = strings.ToLower(strings.TrimSpace(rel))
+		if !ok || strings.TrimSpace(href) == "" {
+			return
+		}
+
+		if rel == "manifest" {
+			manifests = append(manifests, resolveURL(baseURL, strings.TrimSpace(href)))
+
+			return
 		}
 
-		return true
+		if !strings.Contains(rel, "icon") {
+			return
+		}
+
+		source := rel
+		if sizes, ok := s.Attr("sizes"); ok && sizes != "" {
+			source += " " + sizes
+		}
+
+		links = append(links, iconLink{Href: resolveURL(baseURL, strings.TrimSpace(href)), Source: source})
 	})
 
-	if faviconHref == "" {
-		return "", "", ErrFaviconLinkTagNotFound
-	}
+	browserConfigs := []string{}
 
-	// handle base64 data
-	if strings.HasPrefix(faviconHref, "data:image") {
-		base64Data := strings.SplitN(faviconHref, ",", 2)
-		if len(base64Data) != 2 {
-			return "", "", ErrInvalidDataURI
+	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		content, ok := s.Attr("content")
+
+		content = strings.TrimSpace(content)
+		if !ok || content == "" {
+			return
 		}
 
-		decoded, err := base64.StdEncoding.DecodeString(base64Data[1])
-		if err != nil {
-			return "", "", err
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "msapplication-tileimage":
+			links = append(links, iconLink{Href: resolveURL(baseURL, content), Source: "msapplication-TileImage"})
+		case "msapplication-config":
+			if !strings.EqualFold(content, "none") {
+				browserConfigs = append(browserConfigs, resolveURL(baseURL, content))
+			}
+		}
+	})
+
+	if all || len(links) == 0 {
+		for _, manifest := range manifests {
+			links = append(links, manifestIcons(ctx, manifest, fetcher)...)
 		}
 
-		return faviconHref, GetFaviconHash(decoded), nil
+		for _, config := range browserConfigs {
+			links = append(links, browserConfigIcons(ctx, config, fetcher)...)
+		}
 	}
 
-	faviconURL := resolveURL(pageURL, faviconHref)
+	return links
+}
 
-	found, favicon, err := getFavicon(faviconURL, ua, client)
+// fetchIcon retrieves the icon pointed by link.
+// If href is:
+// - A full URL → use as-is.
+// - Data URL → decode and hash directly.
+func fetchIcon(ctx context.Context, link iconLink, fetcher Fetcher, options *input.Options) (Favicon, error) {
+	if isDataURI(link.Href) {
+		return dataURIIcon(link, options)
+	}
+
+	faviconURL := link.Href
+
+	found, hashes, redirects, err := getFavicon(ctx, faviconURL, fetcher, options)
 	if err != nil {
-		return faviconURL, "", err
+		return Favicon{URL: faviconURL, Source: link.Source, Redirects: redirects}, err
 	}
 
 	if !found {
-		return "", "", ErrFaviconNotFound
+		return Favicon{}, ErrFaviconNotFound
 	}
 
-	return faviconURL, favicon, nil
+	return Favicon{URL: faviconURL, Source: link.Source, Redirects: redirects, Hashes: hashes}, nil
+}
+
+// pageBaseURL returns the URL used to resolve the relative URLs of the page:
+// the first <base href> (resolved against the page URL) or the page URL
+// after redirects.
+func pageBaseURL(doc *goquery.Document, pageURL, finalURL string) string {
+	if finalURL != "" {
+		pageURL = finalURL
+	}
+
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok && strings.TrimSpace(href) != "" {
+		return resolveURL(pageURL, strings.TrimSpace(href))
+	}
+
+	return pageURL
+}
+
+// dataURIIcon decodes an icon embedded in a data: URI. The favicon URL
+// is a short identifier (see DataURI.Identifier), not the whole URI.
+func dataURIIcon(link iconLink, options *input.Options) (Favicon, error) {
+	dataURI, err := ParseDataURI(link.Href)
+	if err != nil {
+		return Favicon{}, err
+	}
+
+	favicon := Favicon{URL: dataURI.Identifier(), Source: link.Source, MediaType: dataURI.MediaType}
+
+	if int64(len(dataURI.Data)) > int64(options.MaxSize)*KB {
+		return favicon, fmt.Errorf("%w: %d bytes", ErrFaviconTooLarge, len(dataURI.Data))
+	}
+
+	if !options.Relaxed {
+		if err := ValidateFavicon(dataURI.MediaType, dataURI.Data); err != nil {
+			return favicon, err
+		}
+	}
+
+	favicon.Hashes = faviconHashes(dataURI.Data, perceptualHashing(options))
+
+	return favicon, nil
 }
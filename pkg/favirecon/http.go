@@ -7,7 +7,10 @@ This repository is under MIT License https://github.com/edoardottt/favirecon/blo
 package favirecon
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -19,6 +22,8 @@ import (
 )
 
 const (
+	KB                  = 1024
+	MaxHTMLSize         = 5 * KB * KB
 	TLSHandshakeTimeout = 10
 	KeepAlive           = 30
 	MaxIdleConns        = 100
@@ -54,42 +59,58 @@ func customClient(options *input.Options) (*http.Client, error) {
 	}
 
 	client := http.Client{
-		Transport: &transport,
-		Timeout:   time.Duration(options.Timeout) * time.Second,
+		Transport:     &transport,
+		Timeout:       time.Duration(options.Timeout) * time.Second,
+		CheckRedirect: checkRedirect(options),
 	}
 
 	return &client, nil
 }
 
-func getFavicon(url, ua string, client *http.Client) (bool, string, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return false, "", err
-	}
+var (
+	ErrFaviconTooLarge = errors.New("favicon too large")
+)
 
+// getFavicon fetches and hashes the favicon at url. It returns also the
+// redirect chain followed to get it (see Response.Redirects).
+func getFavicon(ctx context.Context, url string, fetcher Fetcher, options *input.Options) (bool, FaviconHashes, []string, error) {
 	gologger.Debug().Msgf("Checking favicon for %s", url)
 
-	req.Header.Add("User-Agent", ua)
-
-	resp, err := client.Do(req)
+	resp, err := fetcher.Fetch(ctx, url)
 	if err != nil {
-		return false, "", err
+		return false, FaviconHashes{}, nil, err
 	}
 
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return false, "", ErrFaviconNotFound
+		return false, FaviconHashes{}, resp.Redirects, ErrFaviconNotFound
+	}
+
+	maxSize := int64(options.MaxSize) * KB
+	if resp.ContentLength > maxSize {
+		return false, FaviconHashes{}, resp.Redirects, fmt.Errorf("%w: %d bytes", ErrFaviconTooLarge, resp.ContentLength)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	// Stop reading as soon as the limit is exceeded.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
 	if err != nil {
-		return false, "", err
+		return false, FaviconHashes{}, resp.Redirects, err
+	}
+
+	if int64(len(body)) > maxSize {
+		return false, FaviconHashes{}, resp.Redirects, fmt.Errorf("%w: more than %d bytes", ErrFaviconTooLarge, maxSize)
 	}
 
 	if len(body) == 0 {
-		return false, "", ErrEmptyBody
+		return false, FaviconHashes{}, resp.Redirects, ErrEmptyBody
+	}
+
+	if !options.Relaxed {
+		if err := ValidateFavicon(resp.Header.Get("Content-Type"), body); err != nil {
+			return false, FaviconHashes{}, resp.Redirects, err
+		}
 	}
 
-	return true, GetFaviconHash(body), nil
+	return true, faviconHashes(body, perceptualHashing(options)), resp.Redirects, nil
 }
@@ -0,0 +1,372 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+const (
+	icoHeaderSize      = 6
+	icoEntrySize       = 16
+	bmpFileHeaderSize  = 14
+	dibInfoHeaderSize  = 40
+	dibMaxPaletteSize  = 256
+	dibCompressionRGB  = 0
+	dibCompressionMask = 3
+	dibMasksSize       = 12
+	icoMaxDimension    = 256
+	// MaxICOFrames is the maximum number of frames of an ICO file
+	// (real icons have less than 16).
+	MaxICOFrames = 32
+)
+
+var (
+	ErrInvalidICO = errors.New("invalid ICO file")
+	ErrInvalidBMP = errors.New("invalid BMP image")
+)
+
+// icoFrame is one of the images contained in an ICO file.
+type icoFrame struct {
+	Width    int
+	Height   int
+	BitCount int
+	Offset   uint32
+	Data     []byte
+}
+
+// parseICO returns the frames of an ICO (or CUR) file.
+func parseICO(body []byte) ([]icoFrame, error) {
+	if len(body) < icoHeaderSize || !(bytes.HasPrefix(body, magicICO) || bytes.HasPrefix(body, magicCUR)) {
+		return nil, ErrInvalidICO
+	}
+
+	count := int(binary.LittleEndian.Uint16(body[4:6]))
+	if count == 0 || len(body) < icoHeaderSize+count*icoEntrySize {
+		return nil, ErrInvalidICO
+	}
+
+	if count > MaxICOFrames {
+		return nil, fmt.Errorf("%w: %d frames", ErrInvalidICO, count)
+	}
+
+	frames := make([]icoFrame, 0, count)
+
+	for i := 0; i < count; i++ {
+		entry := body[icoHeaderSize+i*icoEntrySize:]
+
+		size := binary.LittleEndian.Uint32(entry[8:12])
+		offset := binary.LittleEndian.Uint32(entry[12:16])
+
+		if uint64(offset)+uint64(size) > uint64(len(body)) {
+			return nil, fmt.Errorf("%w: frame %d out of bounds", ErrInvalidICO, i)
+		}
+
+		frames = append(frames, icoFrame{
+			Width:    icoDimension(entry[0]),
+			Height:   icoDimension(entry[1]),
+			BitCount: int(binary.LittleEndian.Uint16(entry[6:8])),
+			Offset:   offset,
+			Data:     body[offset : offset+size],
+		})
+	}
+
+	return frames, nil
+}
+
+// FrameHashes contains the hashes of an image embedded in an ICO file.
+// Index starts from 1, BitCount is 0 if not declared.
+type FrameHashes struct {
+	Index    int
+	Width    int
+	Height   int
+	BitCount int
+	Hashes   FaviconHashes
+}
+
+// String describes the frame, e.g. "32x32 32bpp #2".
+func (f FrameHashes) String() string {
+	out := fmt.Sprintf("%dx%d", f.Width, f.Height)
+	if f.BitCount != 0 {
+		out += fmt.Sprintf(" %dbpp", f.BitCount)
+	}
+
+	return fmt.Sprintf("%s #%d", out, f.Index)
+}
+
+// icoFrameHashes hashes each frame of an ICO file (decoding it for
+// the perceptual hash only if perceptual is true), nil if the file
+// is not valid. Entries pointing to the same data are hashed only once.
+func icoFrameHashes(body []byte, perceptual bool) []FrameHashes {
+	frames, err := parseICO(body)
+	if err != nil {
+		return nil
+	}
+
+	type location struct {
+		offset uint32
+		size   int
+	}
+
+	result := make([]FrameHashes, 0, len(frames))
+	seen := map[location]FaviconHashes{}
+
+	for i, frame := range frames {
+		loc := location{offset: frame.Offset, size: len(frame.Data)}
+
+		hashes, ok := seen[loc]
+		if !ok {
+			hashes = contentHashes(frame.Data)
+
+			if perceptual {
+				if img, err := decodeICOFrame(frame); err == nil {
+					hashes.DHash = fmt.Sprintf("%016x", DHash(img))
+				}
+			}
+
+			seen[loc] = hashes
+		}
+
+		result = append(result, FrameHashes{
+			Index:    i + 1,
+			Width:    frame.Width,
+			Height:   frame.Height,
+			BitCount: frame.BitCount,
+			Hashes:   hashes,
+		})
+	}
+
+	return result
+}
+
+// icoDimension decodes a frame dimension, 0 means 256 pixels.
+func icoDimension(b byte) int {
+	if b == 0 {
+		return icoMaxDimension
+	}
+
+	return int(b)
+}
+
+// decodeICO decodes the largest frame of an ICO file
+// (the one with more colors if more frames have the same size).
+func decodeICO(body []byte) (image.Image, error) {
+	frames, err := parseICO(body)
+	if err != nil {
+		return nil, err
+	}
+
+	best := frames[0]
+
+	for _, frame := range frames[1:] {
+		if frame.Width*frame.Height > best.Width*best.Height ||
+			(frame.Width*frame.Height == best.Width*best.Height && frame.BitCount > best.BitCount) {
+			best = frame
+		}
+	}
+
+	return decodeICOFrame(best)
+}
+
+// decodeICOFrame decodes a frame, stored as PNG or as a DIB (a BMP
+// without file header, having double height for the AND mask).
+// The size of PNG frames is checked before decoding (decompression
+// bombs), it must match the directory entry.
+func decodeICOFrame(frame icoFrame) (image.Image, error) {
+	if bytes.HasPrefix(frame.Data, magicPNG) {
+		config, err := png.DecodeConfig(bytes.NewReader(frame.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		if config.Width > MaxImageDimension || config.Height > MaxImageDimension {
+			return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+		}
+
+		if config.Width != frame.Width || config.Height != frame.Height {
+			return nil, fmt.Errorf("%w: frame size %dx%d, declared %dx%d",
+				ErrInvalidICO, config.Width, config.Height, frame.Width, frame.Height)
+		}
+
+		return png.Decode(bytes.NewReader(frame.Data))
+	}
+
+	return decodeDIB(frame.Data, -1, true)
+}
+
+// decodeBMP decodes a BMP file.
+func decodeBMP(body []byte) (image.Image, error) {
+	if len(body) < bmpFileHeaderSize || !bytes.HasPrefix(body, []byte("BM")) {
+		return nil, ErrInvalidBMP
+	}
+
+	offset := int(binary.LittleEndian.Uint32(body[10:14]))
+
+	return decodeDIB(body[bmpFileHeaderSize:], offset-bmpFileHeaderSize, false)
+}
+
+// decodeDIB decodes an uncompressed device-independent bitmap (1, 4, 8, 24
+// and 32 bits per pixel). pixels is the offset of the pixel array, -1 if it
+// follows the palette. Icon DIBs have double height and an AND mask
+// marking the transparent pixels.
+//
+//nolint:gocognit,gocyclo,cyclop
+func decodeDIB(data []byte, pixels int, icon bool) (image.Image, error) {
+	if len(data) < dibInfoHeaderSize {
+		return nil, ErrInvalidBMP
+	}
+
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12])))
+	bitCount := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+
+	if headerSize < dibInfoHeaderSize || headerSize > len(data) {
+		return nil, fmt.Errorf("%w: header size %d", ErrInvalidBMP, headerSize)
+	}
+
+	if compression != dibCompressionRGB && !(compression == dibCompressionMask && bitCount == 32) {
+		return nil, fmt.Errorf("%w: unsupported compression %d", ErrInvalidBMP, compression)
+	}
+
+	if icon {
+		height /= 2
+	}
+
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+
+	if width <= 0 || height <= 0 || width > MaxImageDimension || height > MaxImageDimension {
+		return nil, fmt.Errorf("%w: size %dx%d", ErrInvalidBMP, width, height)
+	}
+
+	// Palette.
+	var palette []color.NRGBA
+
+	offset := headerSize
+	if compression == dibCompressionMask && headerSize == dibInfoHeaderSize {
+		offset += dibMasksSize // RGB masks following the header.
+	}
+
+	switch bitCount {
+	case 1, 4, 8:
+		if colorsUsed == 0 || colorsUsed > 1<<bitCount {
+			colorsUsed = 1 << bitCount
+		}
+
+		if colorsUsed > dibMaxPaletteSize || offset+colorsUsed*4 > len(data) {
+			return nil, fmt.Errorf("%w: palette out of bounds", ErrInvalidBMP)
+		}
+
+		palette = make([]color.NRGBA, colorsUsed)
+		for i := range palette {
+			p := data[offset+i*4:]
+			palette[i] = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+		}
+
+		offset += colorsUsed * 4
+	case 24, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported bit count %d", ErrInvalidBMP, bitCount)
+	}
+
+	if pixels >= 0 {
+		offset = pixels
+	}
+
+	stride := ((width*bitCount + 31) / 32) * 4
+	maskStride := ((width + 31) / 32) * 4
+
+	if offset < 0 || offset+stride*height > len(data) {
+		return nil, fmt.Errorf("%w: pixels out of bounds", ErrInvalidBMP)
+	}
+
+	// The AND mask is optional in practice, ignore it if truncated.
+	mask := []byte(nil)
+	if icon && offset+stride*height+maskStride*height <= len(data) {
+		mask = data[offset+stride*height : offset+stride*height+maskStride*height]
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+
+	for y := 0; y < height; y++ {
+		row := y
+		if !topDown {
+			row = height - 1 - y
+		}
+
+		line := data[offset+row*stride : offset+(row+1)*stride]
+
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+
+			switch bitCount {
+			case 1:
+				c = palette[min(int(line[x/8]>>(7-x%8)&1), len(palette)-1)]
+			case 4:
+				c = palette[min(int(line[x/2]>>(4*(1-x%2))&0x0f), len(palette)-1)]
+			case 8:
+				c = palette[min(int(line[x]), len(palette)-1)]
+			case 24:
+				c = color.NRGBA{R: line[x*3+2], G: line[x*3+1], B: line[x*3], A: 0xff}
+			case 32:
+				c = color.NRGBA{R: line[x*4+2], G: line[x*4+1], B: line[x*4], A: line[x*4+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			}
+
+			if mask != nil && mask[row*maskStride+x/8]>>(7-x%8)&1 == 1 {
+				c.A = 0
+			}
+
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// 32 bits images without alpha channel (all zeroes) are opaque.
+	if bitCount == 32 && !hasAlpha {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+
+		if mask != nil {
+			return decodeDIBMask(img, mask, maskStride, topDown), nil
+		}
+	}
+
+	return img, nil
+}
+
+// decodeDIBMask applies the AND mask to an opaque image.
+func decodeDIBMask(img *image.NRGBA, mask []byte, maskStride int, topDown bool) *image.NRGBA {
+	height := img.Rect.Dy()
+
+	for y := 0; y < height; y++ {
+		row := y
+		if !topDown {
+			row = height - 1 - y
+		}
+
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if mask[row*maskStride+x/8]>>(7-x%8)&1 == 1 {
+				img.Pix[img.PixOffset(x, y)+3] = 0
+			}
+		}
+	}
+
+	return img
+}
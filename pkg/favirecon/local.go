@@ -0,0 +1,105 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// pushFiles sends the local favicon files to the input channel,
+// walking the directories recursively. Files larger than the
+// maximum favicon size are skipped.
+func pushFiles(ctx context.Context, r *Runner) {
+	defer close(r.Input)
+
+	maxSize := int64(r.Options.MaxSize) * KB
+
+	for _, path := range r.Options.Files {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			if info.Size() > maxSize {
+				gologger.Info().Msgf("Skipping %s: %s (%d bytes)", p, ErrFaviconTooLarge, info.Size())
+
+				return nil
+			}
+
+			if !skip(r, p) {
+				select {
+				case r.Input <- p:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			return nil
+		})
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			gologger.Error().Msgf("%s", err)
+		}
+	}
+}
+
+// executeLocal hashes the local favicon files and looks them up
+// in the database, without any network traffic.
+func executeLocal(ctx context.Context, r *Runner) {
+	defer r.InWg.Done()
+
+	for i := 0; i < r.Options.Concurrency; i++ {
+		r.InWg.Add(1)
+
+		go func() {
+			defer r.InWg.Done()
+
+			for {
+				path, ok := nextInput(ctx, r)
+				if !ok {
+					return
+				}
+
+				content, err := os.ReadFile(path)
+				if err != nil {
+					gologger.Error().Msgf("%s", err)
+
+					continue
+				}
+
+				if len(content) == 0 {
+					gologger.Debug().Msgf("%s for file %s", ErrEmptyBody, path)
+
+					continue
+				}
+
+				if result, ok := r.Scanner.match(ctx, path, "", Favicon{Hashes: GetFaviconHashes(content)}); ok {
+					r.Output <- result
+				}
+
+				complete(r, path)
+			}
+		}()
+	}
+}
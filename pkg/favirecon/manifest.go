@@ -0,0 +1,130 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	MaxResourceSize = KB * KB
+)
+
+var (
+	ErrResourceNotFetched = errors.New("failed to fetch resource")
+)
+
+// webManifest is a Web App Manifest (manifest.json, site.webmanifest).
+type webManifest struct {
+	Icons []struct {
+		Src   string `json:"src"`
+		Sizes string `json:"sizes"`
+	} `json:"icons"`
+}
+
+// browserConfig is a Microsoft browserconfig.xml file.
+type browserConfig struct {
+	Tile struct {
+		Logos []struct {
+			XMLName xml.Name
+			Src     string `xml:"src,attr"`
+		} `xml:",any"`
+	} `xml:"msapplication>tile"`
+}
+
+// fetchResource fetches a small resource (manifest, browserconfig)
+// and returns its content.
+func fetchResource(ctx context.Context, resourceURL string, fetcher Fetcher) ([]byte, error) {
+	resp, err := fetcher.Fetch(ctx, resourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrResourceNotFetched
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, MaxResourceSize))
+}
+
+// manifestIcons returns the icons declared in a Web App Manifest.
+// Icon URLs are resolved against the manifest URL.
+func manifestIcons(ctx context.Context, manifestURL string, fetcher Fetcher) []iconLink {
+	content, err := fetchResource(ctx, manifestURL, fetcher)
+	if err != nil {
+		gologger.Debug().Msgf("Manifest %s not fetched: %s", manifestURL, err)
+
+		return nil
+	}
+
+	var manifest webManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		gologger.Debug().Msgf("Manifest %s not parsed: %s", manifestURL, err)
+
+		return nil
+	}
+
+	links := []iconLink{}
+
+	for _, icon := range manifest.Icons {
+		if strings.TrimSpace(icon.Src) == "" {
+			continue
+		}
+
+		source := "manifest"
+		if icon.Sizes != "" {
+			source += " " + icon.Sizes
+		}
+
+		links = append(links, iconLink{Href: resolveURL(manifestURL, strings.TrimSpace(icon.Src)), Source: source})
+	}
+
+	return links
+}
+
+// browserConfigIcons returns the tile images declared in a browserconfig.xml file.
+// Icon URLs are resolved against the browserconfig URL.
+func browserConfigIcons(ctx context.Context, configURL string, fetcher Fetcher) []iconLink {
+	content, err := fetchResource(ctx, configURL, fetcher)
+	if err != nil {
+		gologger.Debug().Msgf("Browserconfig %s not fetched: %s", configURL, err)
+
+		return nil
+	}
+
+	var config browserConfig
+	if err := xml.Unmarshal(content, &config); err != nil {
+		gologger.Debug().Msgf("Browserconfig %s not parsed: %s", configURL, err)
+
+		return nil
+	}
+
+	links := []iconLink{}
+
+	for _, logo := range config.Tile.Logos {
+		if strings.TrimSpace(logo.Src) == "" {
+			continue
+		}
+
+		links = append(links, iconLink{
+			Href:   resolveURL(configURL, strings.TrimSpace(logo.Src)),
+			Source: "browserconfig " + logo.XMLName.Local,
+		})
+	}
+
+	return links
+}
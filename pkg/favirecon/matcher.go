@@ -0,0 +1,52 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"context"
+	"errors"
+
+	"github.com/edoardottt/favirecon/pkg/output"
+)
+
+const (
+	HashMatcherName = "hash"
+)
+
+// Matcher identifies the products using a favicon.
+// Matchers are tried in order and the first one returning
+// signatures wins, its name is reported in the result.
+// Implementations must be safe for concurrent use.
+type Matcher interface {
+	// Name returns the name of the matcher.
+	Name() string
+	// Match returns the signatures identifying the favicon, none if
+	// the favicon is not known. An error is returned only if the
+	// matcher failed (e.g. a remote service is not available).
+	Match(ctx context.Context, favicon Favicon) (output.Signatures, error)
+}
+
+// HashMatcher is the default Matcher, looking up the
+// favicon hashes (murmur3, MD5, SHA-256) in a Database.
+type HashMatcher struct {
+	Database Database
+}
+
+// Name returns "hash".
+func (m *HashMatcher) Name() string {
+	return HashMatcherName
+}
+
+// Match looks up the favicon hashes in the database.
+func (m *HashMatcher) Match(_ context.Context, favicon Favicon) (output.Signatures, error) {
+	signatures, err := m.Database.Check(favicon.Hashes, nil)
+	if errors.Is(err, ErrHashNotFound) {
+		return nil, nil
+	}
+
+	return signatures, err
+}
@@ -0,0 +1,293 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"maps"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/edoardottt/favirecon/pkg/output"
+)
+
+const (
+	PerceptualMatcherName = "dhash"
+	// PerceptualHashPrefix prefixes the perceptual hashes in the database.
+	PerceptualHashPrefix = PerceptualMatcherName + ":"
+	MaxImageDimension    = 512
+	// MaxPerceptualDistance is the maximum distance between two hashes (64 bits).
+	MaxPerceptualDistance = 64
+
+	dhashWidth  = 9
+	dhashHeight = 8
+)
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrImageTooLarge    = errors.New("image too large")
+)
+
+// DecodeImage decodes a PNG, GIF, JPEG, ICO or BMP image.
+// For ICO files the largest frame is decoded.
+func DecodeImage(body []byte) (image.Image, error) {
+	format := SniffImageType(body)
+
+	switch format {
+	case FormatICO:
+		return decodeICO(body)
+	case FormatBMP:
+		return decodeBMP(body)
+	case FormatPNG, FormatGIF, FormatJPEG:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImage, format)
+	}
+
+	// Check the size before decoding (decompression bombs).
+	config, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Width > MaxImageDimension || config.Height > MaxImageDimension {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	switch format {
+	case FormatPNG:
+		return png.Decode(bytes.NewReader(body))
+	case FormatGIF:
+		return gif.Decode(bytes.NewReader(body))
+	default:
+		return jpeg.Decode(bytes.NewReader(body))
+	}
+}
+
+// DHash computes the difference hash of an image: the image is reduced
+// to 9x8 grayscale pixels (transparent pixels are drawn on white) and
+// each bit tells if a pixel is brighter than the one on its right.
+// Similar images have hashes with a small Hamming distance.
+func DHash(img image.Image) uint64 {
+	gray := downscaleGray(img, dhashWidth, dhashHeight)
+
+	var hash uint64
+
+	for y := 0; y < dhashHeight; y++ {
+		for x := 0; x < dhashWidth-1; x++ {
+			hash <<= 1
+
+			if gray[y*dhashWidth+x] > gray[y*dhashWidth+x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// downscaleGray reduces the image to width x height grayscale pixels,
+// averaging the pixels of each area.
+func downscaleGray(img image.Image, width, height int) []float64 {
+	bounds := img.Bounds()
+	sums := make([]float64, width*height)
+	counts := make([]float64, width*height)
+	rgba := pixelReader(img)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		cy := (y - bounds.Min.Y) * height / bounds.Dy()
+
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cx := (x - bounds.Min.X) * width / bounds.Dx()
+
+			// Premultiplied 16 bits values, composed over white.
+			r, g, b, a := rgba(x, y)
+			white := float64(0xffff - a)
+			lum := 0.299*(float64(r)+white) + 0.587*(float64(g)+white) + 0.114*(float64(b)+white)
+
+			sums[cy*width+cx] += lum
+			counts[cy*width+cx]++
+		}
+	}
+
+	for i := range sums {
+		if counts[i] != 0 {
+			sums[i] /= counts[i]
+		}
+	}
+
+	return sums
+}
+
+// pixelReader returns a function returning the same values of
+// img.At(x, y).RGBA(), reading the pixels of the image types
+// returned by the decoders directly (without allocations).
+func pixelReader(img image.Image) func(x, y int) (r, g, b, a uint32) {
+	switch m := img.(type) {
+	case *image.NRGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			p := m.Pix[m.PixOffset(x, y):]
+			a := uint32(p[3]) * 0x101
+
+			return uint32(p[0]) * 0x101 * a / 0xffff, uint32(p[1]) * 0x101 * a / 0xffff,
+				uint32(p[2]) * 0x101 * a / 0xffff, a
+		}
+	case *image.RGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			p := m.Pix[m.PixOffset(x, y):]
+
+			return uint32(p[0]) * 0x101, uint32(p[1]) * 0x101, uint32(p[2]) * 0x101, uint32(p[3]) * 0x101
+		}
+	case *image.Gray:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			v := uint32(m.Pix[m.PixOffset(x, y)]) * 0x101
+
+			return v, v, v, 0xffff
+		}
+	case *image.Paletted:
+		palette := make([][4]uint32, len(m.Palette))
+		for i, c := range m.Palette {
+			palette[i][0], palette[i][1], palette[i][2], palette[i][3] = c.RGBA()
+		}
+
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			i := int(m.Pix[m.PixOffset(x, y)])
+			if i >= len(palette) {
+				// Out of palette: image.Paletted returns transparent black.
+				return 0, 0, 0, 0
+			}
+
+			return palette[i][0], palette[i][1], palette[i][2], palette[i][3]
+		}
+	case *image.YCbCr:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return m.YCbCrAt(x, y).RGBA()
+		}
+	default:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return img.At(x, y).RGBA()
+		}
+	}
+}
+
+// PerceptualHash decodes the image and returns its difference
+// hash as 16 hexadecimal digits.
+func PerceptualHash(body []byte) (string, error) {
+	img, err := DecodeImage(body)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%016x", DHash(img)), nil
+}
+
+// perceptualDistance returns the Hamming distance between two
+// perceptual hashes.
+func perceptualDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// SimilarityMatcher is a Matcher identifying favicons similar to
+// the known ones. The distance from the closest known favicon is
+// reported in the results.
+type SimilarityMatcher interface {
+	Matcher
+	// MatchSimilar returns the signatures of the most similar favicon
+	// and its distance, no signatures if no favicon is similar enough.
+	MatchSimilar(ctx context.Context, favicon Favicon) (output.Signatures, int, error)
+}
+
+// perceptualReference is a perceptual hash in the database.
+type perceptualReference struct {
+	hash       uint64
+	signatures output.Signatures
+}
+
+// PerceptualMatcher identifies the favicons having a perceptual hash
+// close to one of the database entries with the "dhash:" prefix.
+type PerceptualMatcher struct {
+	references  []perceptualReference
+	maxDistance int
+}
+
+// NewPerceptualMatcher returns a matcher using the perceptual hashes of the
+// database, accepting favicons within maxDistance bits. Malformed
+// entries are ignored.
+func NewPerceptualMatcher(database Database, maxDistance int) *PerceptualMatcher {
+	m := &PerceptualMatcher{maxDistance: maxDistance}
+
+	// Sorted, ties are resolved always in the same way.
+	for _, key := range slices.Sorted(maps.Keys(database)) {
+		hash, ok := strings.CutPrefix(key, PerceptualHashPrefix)
+		if !ok {
+			continue
+		}
+
+		value, err := strconv.ParseUint(hash, 16, 64)
+		if err != nil {
+			continue
+		}
+
+		m.references = append(m.references, perceptualReference{hash: value, signatures: database[key]})
+	}
+
+	return m
+}
+
+// Len returns the number of perceptual hashes known.
+func (m *PerceptualMatcher) Len() int {
+	return len(m.references)
+}
+
+// Name returns "dhash".
+func (m *PerceptualMatcher) Name() string {
+	return PerceptualMatcherName
+}
+
+// Match returns the signatures of the most similar favicon.
+func (m *PerceptualMatcher) Match(ctx context.Context, favicon Favicon) (output.Signatures, error) {
+	signatures, _, err := m.MatchSimilar(ctx, favicon)
+
+	return signatures, err
+}
+
+// MatchSimilar returns the signatures of the most similar favicon and its distance.
+func (m *PerceptualMatcher) MatchSimilar(_ context.Context, favicon Favicon) (output.Signatures, int, error) {
+	if favicon.Hashes.DHash == "" {
+		return nil, 0, nil
+	}
+
+	hash, err := strconv.ParseUint(favicon.Hashes.DHash, 16, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		best     output.Signatures
+		distance = MaxPerceptualDistance + 1
+	)
+
+	for _, reference := range m.references {
+		if d := perceptualDistance(hash, reference.hash); d < distance {
+			best, distance = reference.signatures, d
+		}
+	}
+
+	if distance > m.maxDistance {
+		return nil, 0, nil
+	}
+
+	return best, distance, nil
+}
@@ -0,0 +1,172 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinPort = 1
+	MaxPort = 65535
+)
+
+var (
+	ErrInvalidPort = errors.New("invalid port")
+)
+
+//nolint:gochecknoglobals
+var (
+	// PortPresets contains named lists of ports usable with -ports.
+	PortPresets = map[string][]int{
+		"top-web": {80, 443, 8000, 8080, 8443, 8888, 9090, 9443, 10000},
+		"web": {80, 81, 443, 591, 2082, 2083, 2086, 2087, 3000, 4443, 5000, 5601, 7001,
+			8000, 8008, 8080, 8081, 8088, 8443, 8888, 9000, 9090, 9443, 10000, 10443},
+	}
+
+	// httpsPorts and httpPorts are used to guess the scheme.
+	httpsPorts = []int{443, 2083, 2087, 4443, 8443, 9443, 10000, 10443}
+	httpPorts  = []int{80, 81, 591, 2082, 2086, 3000, 8000, 8008, 8080, 8081, 8088, 8888}
+)
+
+// ParsePorts parses a comma separated list of ports, port ranges
+// (e.g. 8000-8100) and presets (see PortPresets).
+// Duplicates are removed, the order is preserved.
+func ParsePorts(spec string) ([]int, error) {
+	ports := []int{}
+	seen := map[int]struct{}{}
+
+	add := func(port int) {
+		if _, ok := seen[port]; !ok {
+			seen[port] = struct{}{}
+			ports = append(ports, port)
+		}
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if preset, ok := PortPresets[strings.ToLower(item)]; ok {
+			for _, port := range preset {
+				add(port)
+			}
+
+			continue
+		}
+
+		first, last, err := parsePortRange(item)
+		if err != nil {
+			return nil, err
+		}
+
+		for port := first; port <= last; port++ {
+			add(port)
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPort, spec)
+	}
+
+	return ports, nil
+}
+
+// parsePortRange parses a single port or a port range.
+func parsePortRange(item string) (int, int, error) {
+	start, end, isRange := strings.Cut(item, "-")
+	if !isRange {
+		end = start
+	}
+
+	first, err := strconv.Atoi(strings.TrimSpace(start))
+	if err != nil || first < MinPort || first > MaxPort {
+		return 0, 0, fmt.Errorf("%w: %s", ErrInvalidPort, item)
+	}
+
+	last, err := strconv.Atoi(strings.TrimSpace(end))
+	if err != nil || last < first || last > MaxPort {
+		return 0, 0, fmt.Errorf("%w: %s", ErrInvalidPort, item)
+	}
+
+	return first, last, nil
+}
+
+// guessScheme returns the scheme commonly used on the port,
+// or an empty string if unknown.
+func guessScheme(port int) string {
+	switch {
+	case containsPort(httpsPorts, port):
+		return "https"
+	case containsPort(httpPorts, port):
+		return "http"
+	}
+
+	return ""
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ExpandPorts returns a target for each port. Targets already
+// having a port are returned as they are.
+// If the target has no scheme and the port is a well-known one,
+// the scheme is guessed, otherwise it's left to the probing strategy.
+func ExpandPorts(target string, ports []int) []string {
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil || u.Port() != "" {
+			return []string{target}
+		}
+
+		targets := make([]string, 0, len(ports))
+
+		for _, port := range ports {
+			u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+			targets = append(targets, u.String())
+		}
+
+		return targets
+	}
+
+	host, path := target, ""
+	if i := strings.Index(target, "/"); i != -1 {
+		host, path = target[:i], target[i:]
+	}
+
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return []string{target}
+	}
+
+	host = strings.Trim(host, "[]")
+	targets := make([]string, 0, len(ports))
+
+	for _, port := range ports {
+		t := net.JoinHostPort(host, strconv.Itoa(port)) + path
+		if scheme := guessScheme(port); scheme != "" {
+			t = scheme + "://" + t
+		}
+
+		targets = append(targets, t)
+	}
+
+	return targets
+}
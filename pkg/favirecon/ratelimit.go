@@ -6,15 +6,37 @@ This repository is under MIT License https://github.com/edoardottt/favirecon/blo
 
 package favirecon
 
-import "go.uber.org/ratelimit"
+import (
+	"context"
 
-func rateLimiter(r *Runner) ratelimit.Limiter {
+	"go.uber.org/ratelimit"
+)
+
+func rateLimiter(rate int) ratelimit.Limiter {
 	var ratelimiter ratelimit.Limiter
-	if r.Options.RateLimit > 0 {
-		ratelimiter = ratelimit.New(r.Options.RateLimit)
+	if rate > 0 {
+		ratelimiter = ratelimit.New(rate)
 	} else {
 		ratelimiter = ratelimit.NewUnlimited()
 	}
 
 	return ratelimiter
 }
+
+// take blocks until the limiter allows a new request or ctx is done.
+// In the latter case it returns ctx.Err() without waiting for the limiter.
+func take(ctx context.Context, limiter ratelimit.Limiter) error {
+	done := make(chan struct{})
+
+	go func() {
+		limiter.Take()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,91 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/edoardottt/favirecon/pkg/input"
+	"github.com/projectdiscovery/gologger"
+)
+
+//nolint:gochecknoglobals
+var (
+	ErrRedirectsDisabled = errors.New("redirects disabled")
+	ErrTooManyRedirects  = errors.New("too many redirects")
+	ErrCrossHostRedirect = errors.New("redirect to another host")
+)
+
+// checkRedirect returns the http.Client CheckRedirect function
+// enforcing the redirect policy of the options. Redirects not
+// allowed are not followed: the redirect response is returned
+// (and the resource is considered not found).
+func checkRedirect(options *input.Options) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if err := allowRedirect(options, via[0].URL, req.URL, len(via)); err != nil {
+			gologger.Debug().Msgf("Not following redirect from %s to %s: %s", via[len(via)-1].URL, req.URL, err)
+
+			return http.ErrUseLastResponse
+		}
+
+		return nil
+	}
+}
+
+// allowRedirect checks if the n-th redirect of a chain starting
+// at from and landing on to is allowed by the options.
+func allowRedirect(options *input.Options, from, to *url.URL, n int) error {
+	switch {
+	case options.NoRedirects:
+		return ErrRedirectsDisabled
+	case n > options.MaxRedirects:
+		return fmt.Errorf("%w (%d)", ErrTooManyRedirects, options.MaxRedirects)
+	case options.SameHostRedirects && !strings.EqualFold(from.Hostname(), to.Hostname()):
+		return fmt.Errorf("%w %s", ErrCrossHostRedirect, to.Hostname())
+	}
+
+	return nil
+}
+
+// allowHTMLRedirect checks if the n-th meta refresh or JavaScript
+// redirect of a chain starting at from and landing on to is allowed.
+func allowHTMLRedirect(options *input.Options, from, to string, n int) error {
+	fromURL, err := url.Parse(from)
+	if err != nil {
+		return err
+	}
+
+	toURL, err := url.Parse(to)
+	if err != nil {
+		return err
+	}
+
+	return allowRedirect(options, fromURL, toURL, n)
+}
+
+// redirectChain returns the URLs requested to get resp, from the
+// first one to the final one, or nil if there were no redirects.
+func redirectChain(resp *http.Response) []string {
+	if resp.Request == nil || resp.Request.Response == nil {
+		return nil
+	}
+
+	chain := []string{resp.Request.URL.String()}
+
+	for r := resp.Request.Response; r != nil && r.Request != nil; r = r.Request.Response {
+		chain = append(chain, r.Request.URL.String())
+	}
+
+	slices.Reverse(chain)
+
+	return chain
+}
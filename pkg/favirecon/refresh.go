@@ -0,0 +1,106 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// MaxHTMLRedirects is the maximum number of meta refresh and
+	// JavaScript redirects followed looking for icons.
+	MaxHTMLRedirects = 3
+)
+
+//nolint:gochecknoglobals
+var (
+	// Delay, separator and optional "url=" of a meta refresh.
+	metaRefreshPrefix = regexp.MustCompile(`(?i)^\s*[\d.]*\s*[;,]?\s*(url\s*=\s*)?`)
+	// Assignments like window.location = "/login", location.href='/login'.
+	jsLocationAssignment = regexp.MustCompile(
+		`(?:\b(?:window|document|top|self|parent)\.)?\blocation(?:\.href)?\s*=\s*["']([^"']+)["']`)
+	// Calls like location.replace("/login"), window.location.assign('/login').
+	jsLocationCall = regexp.MustCompile(`\blocation\.(?:replace|assign)\(\s*["']([^"']+)["']\s*\)`)
+)
+
+// htmlRedirect returns the absolute URL of the page redirect, the
+// meta refresh first, then simple JavaScript location assignments in
+// inline scripts. It returns an empty string if there is no redirect
+// to an HTTP(S) URL.
+func htmlRedirect(doc *goquery.Document, baseURL string) string {
+	target := ""
+
+	doc.Find("meta[http-equiv]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		equiv, _ := s.Attr("http-equiv")
+		if !strings.EqualFold(strings.TrimSpace(equiv), "refresh") {
+			return true
+		}
+
+		content, _ := s.Attr("content")
+		target = metaRefreshURL(content)
+
+		return target == ""
+	})
+
+	if target == "" {
+		doc.Find("script").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if _, ok := s.Attr("src"); ok {
+				return true
+			}
+
+			target = jsLocation(s.Text())
+
+			return target == ""
+		})
+	}
+
+	if target == "" {
+		return ""
+	}
+
+	next := resolveURL(baseURL, target)
+
+	u, err := url.Parse(next)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	// Fragments are not sent to the server.
+	u.Fragment = ""
+
+	return u.String()
+}
+
+// metaRefreshURL returns the URL of a meta refresh content
+// (e.g. "0; url=/login"), empty if there is none.
+func metaRefreshURL(content string) string {
+	loc := metaRefreshPrefix.FindStringIndex(content)
+	if loc == nil {
+		return ""
+	}
+
+	target := strings.TrimSpace(content[loc[1]:])
+	target = strings.Trim(target, `"'`)
+
+	return strings.TrimSpace(target)
+}
+
+// jsLocation returns the URL of the first location assignment
+// (or location.replace/assign call) in a script.
+func jsLocation(script string) string {
+	for _, re := range []*regexp.Regexp{jsLocationAssignment, jsLocationCall} {
+		if m := re.FindStringSubmatch(script); m != nil {
+			return strings.TrimSpace(m[1])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,141 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	CheckpointFlushInterval = 5 * time.Second
+)
+
+// Checkpoint records the targets completely processed,
+// one per line, in order to resume interrupted scans.
+// The targets already in the file are loaded when it is opened.
+type Checkpoint struct {
+	path      string
+	completed map[string]struct{}
+	mutex     sync.Mutex
+	file      *os.File
+	writer    *bufio.Writer
+	done      chan struct{}
+}
+
+// OpenCheckpoint opens (or creates) the resume file at path.
+// The file is flushed every CheckpointFlushInterval and on Close.
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	completed := map[string]struct{}{}
+
+	previous, err := os.Open(path)
+
+	switch {
+	case err == nil:
+		scanner := bufio.NewScanner(previous)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				completed[line] = struct{}{}
+			}
+		}
+
+		err = scanner.Err()
+		_ = previous.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Checkpoint{
+		path:      path,
+		completed: completed,
+		file:      file,
+		writer:    bufio.NewWriter(file),
+		done:      make(chan struct{}),
+	}
+
+	go c.flushPeriodically()
+
+	return c, nil
+}
+
+// Len returns the number of targets completed in previous runs.
+func (c *Checkpoint) Len() int {
+	return len(c.completed)
+}
+
+// Completed checks if target has been completed in a previous run.
+func (c *Checkpoint) Completed(target string) bool {
+	_, ok := c.completed[target]
+
+	return ok
+}
+
+// Add records target as completed.
+func (c *Checkpoint) Add(target string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, err := c.writer.WriteString(target + "\n")
+
+	return err
+}
+
+// Flush writes the buffered targets to the file.
+func (c *Checkpoint) Flush() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.writer.Flush()
+}
+
+// Close flushes and closes the file.
+func (c *Checkpoint) Close() error {
+	close(c.done)
+
+	if err := c.Flush(); err != nil {
+		_ = c.file.Close()
+
+		return err
+	}
+
+	return c.file.Close()
+}
+
+// Remove closes and deletes the file, used once the scan is completed.
+func (c *Checkpoint) Remove() error {
+	if err := c.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(c.path)
+}
+
+func (c *Checkpoint) flushPeriodically() {
+	ticker := time.NewTicker(CheckpointFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			_ = c.Flush()
+		}
+	}
+}
@@ -0,0 +1,699 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edoardottt/favirecon/pkg/input"
+	"github.com/edoardottt/favirecon/pkg/output"
+	"github.com/edoardottt/golazy"
+	"github.com/projectdiscovery/gologger"
+	"go.uber.org/ratelimit"
+)
+
+//nolint:gochecknoglobals
+var (
+	ErrProbeFailed = errors.New("no scheme could be probed for")
+)
+
+// Result is a favicon found by the Scanner.
+type Result = output.Found
+
+// Scanner looks for the favicons of the targets and identifies
+// them using the signature database.
+// It can be used to embed favirecon in other tools: it never reads
+// stdin nor writes to stdout, errors are returned to the caller.
+// A Scanner is safe for concurrent use.
+type Scanner struct {
+	options   input.Options
+	db        Database
+	client    *http.Client
+	userAgent string
+	fetcher   Fetcher
+	matchers  []Matcher
+	limiter   ratelimit.Limiter
+	stats     *Stats
+}
+
+// Option configures a Scanner.
+type Option func(*Scanner) error
+
+// StreamFunc receives the results of a target scanned by Stream,
+// or the error that prevented the scan.
+type StreamFunc func(target string, results []Result, err error)
+
+// NewScanner returns a Scanner using the embedded database and the
+// same defaults as the command line tool, modified by opts.
+func NewScanner(opts ...Option) (*Scanner, error) {
+	s := &Scanner{
+		options: input.Options{
+			Concurrency:   input.DefaultConcurrency,
+			Timeout:       input.DefaultTimeout,
+			RateLimit:     input.DefaultRateLimit,
+			MaxSize:       input.DefaultMaxSize,
+			Probe:         input.DefaultProbe,
+			PHashDistance: input.DefaultPHashDistance,
+			MaxRedirects:  input.DefaultMaxRedirects,
+		},
+		db:        db,
+		userAgent: golazy.GenerateRandomUserAgent(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.fetcher == nil {
+		if s.client == nil {
+			client, err := customClient(&s.options)
+			if err != nil {
+				return nil, err
+			}
+
+			s.client = client
+		}
+
+		s.fetcher = &HTTPFetcher{Client: s.client, UserAgent: s.userAgent}
+	}
+
+	matchers := []Matcher{&HashMatcher{Database: s.db}, &SVGMatcher{Database: s.db}}
+
+	if s.options.PHashDistance >= 0 {
+		if m := NewPerceptualMatcher(s.db, s.options.PHashDistance); m.Len() != 0 {
+			matchers = append(matchers, m)
+		} else {
+			// Favicons are not decoded for nothing.
+			gologger.Debug().Msgf("Perceptual matching disabled: no %s entries in the database", PerceptualHashPrefix)
+
+			s.options.PHashDistance = -1
+		}
+	}
+
+	s.matchers = append(matchers, s.matchers...)
+	s.limiter = rateLimiter(s.options.RateLimit)
+
+	return s, nil
+}
+
+// withOptions uses the command line options, the unset
+// ones use the default values.
+func withOptions(options input.Options) Option {
+	return func(s *Scanner) error {
+		options.ApplyDefaults()
+		s.options = options
+
+		return nil
+	}
+}
+
+// WithConcurrency sets the number of targets scanned in parallel by Stream.
+func WithConcurrency(concurrency int) Option {
+	return func(s *Scanner) error {
+		if concurrency <= 0 {
+			return fmt.Errorf("concurrency: %w", input.ErrNegativeValue)
+		}
+
+		s.options.Concurrency = concurrency
+
+		return nil
+	}
+}
+
+// WithTimeout sets the connection timeout, rounded up to seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Scanner) error {
+		if timeout <= 0 {
+			return fmt.Errorf("timeout: %w", input.ErrNegativeValue)
+		}
+
+		s.options.Timeout = int(math.Ceil(timeout.Seconds()))
+
+		return nil
+	}
+}
+
+// WithRateLimit sets the maximum number of targets scanned per second.
+func WithRateLimit(rate int) Option {
+	return func(s *Scanner) error {
+		if rate < 0 {
+			return fmt.Errorf("rate limit: %w", input.ErrNegativeValue)
+		}
+
+		s.options.RateLimit = rate
+
+		return nil
+	}
+}
+
+// WithProxy sets the proxy server (URL).
+// It's ignored if WithHTTPClient or WithFetcher is used.
+func WithProxy(proxy string) Option {
+	return func(s *Scanner) error {
+		s.options.Proxy = proxy
+
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header, random by default.
+// It's ignored if WithFetcher is used.
+func WithUserAgent(userAgent string) Option {
+	return func(s *Scanner) error {
+		s.userAgent = userAgent
+
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for all the requests.
+// It's ignored if WithFetcher is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Scanner) error {
+		s.client = client
+
+		return nil
+	}
+}
+
+// WithFetcher sets the Fetcher used to retrieve the resources,
+// replacing the default HTTPFetcher.
+func WithFetcher(fetcher Fetcher) Option {
+	return func(s *Scanner) error {
+		s.fetcher = fetcher
+
+		return nil
+	}
+}
+
+// WithDatabase sets the signature database (see LoadDatabases).
+func WithDatabase(database Database) Option {
+	return func(s *Scanner) error {
+		if len(database) == 0 {
+			return ErrEmptyDatabase
+		}
+
+		s.db = database
+
+		return nil
+	}
+}
+
+// WithPerceptualDistance sets the maximum perceptual hash distance (0-64)
+// of the favicons similar to the database ones, -1 disables the
+// PerceptualMatcher.
+func WithPerceptualDistance(distance int) Option {
+	return func(s *Scanner) error {
+		if distance < -1 || distance > input.MaxPHashDistance {
+			return fmt.Errorf("phash distance: %w %d", input.ErrInvalidValue, distance)
+		}
+
+		s.options.PHashDistance = distance
+
+		return nil
+	}
+}
+
+// WithMatchers adds matchers to the default ones (HashMatcher, SVGMatcher,
+// then PerceptualMatcher if the database has perceptual hashes).
+// Matchers are tried in order, the default ones first.
+func WithMatchers(matchers ...Matcher) Option {
+	return func(s *Scanner) error {
+		s.matchers = append(s.matchers, matchers...)
+
+		return nil
+	}
+}
+
+// WithHashes returns only the favicons having one of these
+// hashes (mmh3, MD5 or SHA-256).
+func WithHashes(hashes ...string) Option {
+	return func(s *Scanner) error {
+		s.options.Hash = hashes
+
+		return nil
+	}
+}
+
+// WithAll returns also the favicons not found in the database
+// and the rejected ones.
+func WithAll() Option {
+	return func(s *Scanner) error {
+		s.options.All = true
+
+		return nil
+	}
+}
+
+// WithAllIcons hashes every icon advertised by the page,
+// not just the first one.
+func WithAllIcons() Option {
+	return func(s *Scanner) error {
+		s.options.AllIcons = true
+
+		return nil
+	}
+}
+
+// WithRelaxed accepts favicons without checking
+// Content-Type and image format.
+func WithRelaxed() Option {
+	return func(s *Scanner) error {
+		s.options.Relaxed = true
+
+		return nil
+	}
+}
+
+// WithMaxSize sets the maximum favicon size in KB.
+func WithMaxSize(size int) Option {
+	return func(s *Scanner) error {
+		if size <= 0 {
+			return fmt.Errorf("max size: %w", input.ErrNegativeValue)
+		}
+
+		s.options.MaxSize = size
+
+		return nil
+	}
+}
+
+// WithNoRedirects disables redirects. The HTTP redirect policy is
+// ignored if WithHTTPClient or WithFetcher is used.
+func WithNoRedirects() Option {
+	return func(s *Scanner) error {
+		s.options.NoRedirects = true
+
+		return nil
+	}
+}
+
+// WithMaxRedirects sets the maximum number of redirects followed.
+func WithMaxRedirects(redirects int) Option {
+	return func(s *Scanner) error {
+		if redirects < 0 {
+			return fmt.Errorf("max redirects: %w", input.ErrNegativeValue)
+		}
+
+		s.options.MaxRedirects = redirects
+
+		return nil
+	}
+}
+
+// WithSameHostRedirects follows only the redirects to the host
+// of the requested URL.
+func WithSameHostRedirects() Option {
+	return func(s *Scanner) error {
+		s.options.SameHostRedirects = true
+
+		return nil
+	}
+}
+
+// WithProbe sets the schemes to try for targets without scheme
+// (input.ProbeHTTP, input.ProbeHTTPS, ...).
+func WithProbe(strategy string) Option {
+	return func(s *Scanner) error {
+		if _, err := ProbeURLs("example.com", strategy); err != nil {
+			return err
+		}
+
+		s.options.Probe = strategy
+
+		return nil
+	}
+}
+
+// Scan looks for the favicons of target and returns the results.
+// Targets without scheme are probed as configured by WithProbe.
+// No results and no error are returned if the target has no
+// favicon or no favicon matches the database.
+// If ctx is done, the results found so far are returned with ctx.Err().
+func (s *Scanner) Scan(ctx context.Context, target string) ([]Result, error) {
+	targets, err := ProbeURLs(target, s.options.Probe)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		results = []Result{}
+		found   bool
+		lastErr error
+	)
+
+	for _, t := range targets {
+		favicons, err := s.scanTarget(ctx, t)
+		if errors.Is(err, ErrMalformedURL) {
+			return results, err
+		}
+
+		if err != nil {
+			lastErr = err
+		}
+
+		for _, favicon := range favicons {
+			if result, ok := s.match(ctx, target, urlScheme(t), favicon); ok {
+				results = append(results, result)
+			}
+
+			found = found || favicon.Err == nil
+		}
+
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+
+		if found && s.options.Probe != input.ProbeBoth {
+			break
+		}
+	}
+
+	switch {
+	case found || lastErr == nil:
+		return results, nil
+	case len(targets) > 1:
+		return results, fmt.Errorf("%w %s: %w", ErrProbeFailed, target, lastErr)
+	}
+
+	return results, lastErr
+}
+
+// Stream scans the targets received from the channel until it's closed
+// or ctx is done, calling fn for each target. Targets are scanned in
+// parallel (see WithConcurrency), but fn is never called concurrently.
+// It returns ctx.Err().
+func (s *Scanner) Stream(ctx context.Context, targets <-chan string, fn StreamFunc) error {
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+
+	for i := 0; i < s.options.Concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				var (
+					target string
+					ok     bool
+				)
+
+				select {
+				case <-ctx.Done():
+					return
+				case target, ok = <-targets:
+					if !ok {
+						return
+					}
+				}
+
+				results, err := s.Scan(ctx, target)
+
+				mutex.Lock()
+				fn(target, results, err)
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+/*
+scanTarget looks for the favicons of a single target URL.
+
+Try /favicon.ico first. Most common and lightweight check.
+Accept it only if:
+- Status is 200.
+- Content-Type is an image.
+- Body length is > 0 (some sites return 200 but empty).
+If valid, hash and lookup. ✅ Done.
+
+Fallback to parsing <link rel="icon" ...> in the HTML <head>:
+- Fetch original input URL (not with /favicon.ico appended).
+- Parse the HTML.
+- Extract: rel=icon, rel=shortcut icon, rel=apple-touch-icon, rel=mask-icon...
+- Extract: msapplication-TileImage meta tag.
+- If nothing is found, follow rel=manifest and msapplication-config (browserconfig.xml).
+
+In all-icons mode both steps are always performed and every
+icon found is hashed and looked up.
+*/
+func (s *Scanner) scanTarget(ctx context.Context, target string) ([]Favicon, error) {
+	faviconURL, err := PrepareURL(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := take(ctx, s.limiter); err != nil {
+		return nil, err
+	}
+
+	if s.options.AllIcons {
+		return collectFavicons(ctx, target, faviconURL, s.fetcher, &s.options), nil
+	}
+
+	favicon, err := discoverFavicon(ctx, target, faviconURL, s.fetcher, &s.options)
+	if err != nil {
+		if isNotFound(err) {
+			gologger.Debug().Msgf("Favicon not found for %s: %s", target, err)
+
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return []Favicon{favicon}, nil
+}
+
+// match identifies the favicon and returns the result,
+// if it has to be reported.
+func (s *Scanner) match(ctx context.Context, value, scheme string, favicon Favicon) (Result, bool) {
+	if favicon.Err != nil {
+		return s.matchRejected(value, scheme, favicon)
+	}
+
+	if s.stats != nil {
+		s.stats.Favicons.Add(1)
+	}
+
+	result := favicon.Hashes
+
+	if len(s.options.Hash) != 0 && !matchHashes(s.options.Hash, result) {
+		gologger.Debug().Msgf("[%s] %s %s", result.MMH3, favicon.URL, ErrHashNotMatching)
+
+		return Result{}, false
+	}
+
+	signatures, matcher, distance, frame := s.identify(ctx, favicon)
+	if len(signatures) == 0 {
+		// Report also unknown favicons in -all mode.
+		if !s.options.All {
+			gologger.Debug().Msgf("[%s] %s %s", result.MMH3, favicon.URL, ErrHashNotFound)
+
+			return Result{}, false
+		}
+
+		signatures = output.Signatures{{Name: UnknownName}}
+	}
+
+	o := Result{
+		URL:        value,
+		Hash:       result.MMH3,
+		MD5:        result.MD5,
+		SHA256:     result.SHA256,
+		FaviconURL: favicon.URL,
+		MediaType:  favicon.MediaType,
+		Scheme:     scheme,
+		Matcher:    matcher,
+		PHash:      result.DHash,
+		SVG:        result.SVG,
+		Distance:   distance,
+	}
+
+	if frame != nil {
+		o.Frame = frame.String()
+	}
+
+	for _, f := range result.Frames {
+		o.Frames = append(o.Frames, output.Frame{
+			Frame:  f.String(),
+			Hash:   f.Hashes.MMH3,
+			MD5:    f.Hashes.MD5,
+			SHA256: f.Hashes.SHA256,
+			PHash:  f.Hashes.DHash,
+		})
+	}
+	o.SetSignatures(signatures)
+	o.SetRedirects(favicon.Redirects)
+
+	// More than one icon per target: tell them apart.
+	if s.options.AllIcons {
+		o.Source = favicon.Source
+	}
+
+	return o, true
+}
+
+// matchRejected returns a favicon rejected because too large or
+// invalid, only in -all mode and if no hash filter is set.
+func (s *Scanner) matchRejected(value, scheme string, favicon Favicon) (Result, bool) {
+	if !s.options.All || len(s.options.Hash) != 0 {
+		return Result{}, false
+	}
+
+	o := Result{
+		URL:        value,
+		Name:       UnknownName,
+		FaviconURL: favicon.URL,
+		MediaType:  favicon.MediaType,
+		Scheme:     scheme,
+		Error:      rejectionCategory(favicon.Err),
+		Reason:     favicon.Err.Error(),
+	}
+
+	o.SetRedirects(favicon.Redirects)
+
+	if s.options.AllIcons {
+		o.Source = favicon.Source
+	}
+
+	return o, true
+}
+
+// identify tries the matchers in order and returns the signatures
+// found by the first one matching, its name and the distance of
+// the favicon if it's a SimilarityMatcher.
+// Each matcher checks the whole file first, then the ICO frames:
+// the matching frame is returned, if any.
+func (s *Scanner) identify(ctx context.Context, favicon Favicon) (output.Signatures, string, *int, *FrameHashes) {
+	for _, m := range s.matchers {
+		signatures, distance, ok := s.identifyWith(ctx, m, favicon)
+		if ok {
+			return signatures, m.Name(), distance, nil
+		}
+
+		for i, frame := range favicon.Hashes.Frames {
+			f := Favicon{URL: favicon.URL, Source: favicon.Source, Hashes: frame.Hashes}
+
+			signatures, distance, ok := s.identifyWith(ctx, m, f)
+			if ok {
+				return signatures, m.Name(), distance, &favicon.Hashes.Frames[i]
+			}
+		}
+	}
+
+	return nil, "", nil, nil
+}
+
+// identifyWith runs a matcher, it returns false if
+// the favicon is not identified.
+func (s *Scanner) identifyWith(ctx context.Context, m Matcher, favicon Favicon) (output.Signatures, *int, bool) {
+	var (
+		signatures output.Signatures
+		distance   *int
+		err        error
+	)
+
+	if sm, ok := m.(SimilarityMatcher); ok {
+		var d int
+
+		signatures, d, err = sm.MatchSimilar(ctx, favicon)
+		distance = &d
+	} else {
+		signatures, err = m.Match(ctx, favicon)
+	}
+
+	if err != nil {
+		gologger.Debug().Msgf("Matcher %s failed for %s: %s", m.Name(), favicon.URL, err)
+
+		return nil, nil, false
+	}
+
+	return signatures, distance, len(signatures) != 0
+}
+
+// discoverFavicon tries /favicon.ico first and falls back
+// to the first icon advertised by the HTML page.
+// If no favicon is found but one has been rejected, the rejected
+// favicon is returned (with Err set).
+func discoverFavicon(ctx context.Context, value, faviconURL string, fetcher Fetcher, options *input.Options) (Favicon, error) {
+	found, result, redirects, err := getFavicon(ctx, faviconURL, fetcher, options)
+	if err != nil {
+		gologger.Debug().Msgf("%s for url %s", err.Error(), value)
+	}
+
+	if found {
+		return Favicon{URL: faviconURL, Source: DefaultFaviconSource, Redirects: redirects, Hashes: result}, nil
+	}
+
+	gologger.Debug().Msgf("Fallback to HTML parsing for %s", value)
+
+	favicon, htmlErr := extractFaviconFromHTML(ctx, value, fetcher, options)
+	if htmlErr == nil {
+		return favicon, nil
+	}
+
+	switch {
+	case isRejected(err):
+		return Favicon{URL: faviconURL, Source: DefaultFaviconSource, Redirects: redirects, Err: err}, nil
+	case isRejected(htmlErr):
+		favicon.Err = htmlErr
+
+		return favicon, nil
+	case err != nil && !isNotFound(err):
+		// Most likely a connection error, more relevant than the HTML one.
+		return Favicon{}, err
+	}
+
+	return Favicon{}, htmlErr
+}
+
+// collectFavicons gathers /favicon.ico and every icon advertised
+// by the HTML page (all-icons mode).
+func collectFavicons(ctx context.Context, value, faviconURL string, fetcher Fetcher, options *input.Options) []Favicon {
+	favicons := []Favicon{}
+
+	found, result, redirects, err := getFavicon(ctx, faviconURL, fetcher, options)
+	if err != nil {
+		gologger.Debug().Msgf("%s for url %s", err.Error(), value)
+	}
+
+	switch {
+	case found:
+		favicons = append(favicons, Favicon{URL: faviconURL, Source: DefaultFaviconSource, Redirects: redirects, Hashes: result})
+	case isRejected(err):
+		favicons = append(favicons, Favicon{URL: faviconURL, Source: DefaultFaviconSource, Redirects: redirects, Err: err})
+	}
+
+	icons, err := extractFaviconsFromHTML(ctx, value, fetcher, options)
+	if err != nil {
+		gologger.Debug().Msgf("No icons found in HTML for %s: %s", value, err)
+	}
+
+	for _, icon := range icons {
+		if !containsFavicon(favicons, icon) {
+			favicons = append(favicons, icon)
+		}
+	}
+
+	return favicons
+}
@@ -0,0 +1,24 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"sync/atomic"
+)
+
+// Stats contains the counters of a scan.
+type Stats struct {
+	// Targets is the number of targets (URLs or files) completely processed.
+	Targets atomic.Int64
+	// Favicons is the number of valid favicons hashed.
+	Favicons atomic.Int64
+	// Results is the number of results written.
+	Results atomic.Int64
+	// Skipped is the number of targets skipped because already
+	// completed (see Checkpoint).
+	Skipped atomic.Int64
+}
@@ -0,0 +1,147 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/edoardottt/favirecon/pkg/output"
+)
+
+const (
+	SVGMatcherName = "svg"
+	// SVGHashPrefix prefixes the canonical SVG hashes in the database.
+	SVGHashPrefix = SVGMatcherName + ":"
+)
+
+var (
+	ErrInvalidSVG = errors.New("invalid SVG")
+)
+
+// CanonicalSVG returns the canonical form of an SVG document, so that
+// documents differing only in formatting have the same hash:
+// comments, processing instructions and directives (XML declaration,
+// DOCTYPE) are removed, whitespace is collapsed, attributes are sorted
+// and empty elements are written with an end tag.
+func CanonicalSVG(body []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Entity = xml.HTMLEntity
+	// The content is not modified, non UTF-8 documents are rare.
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var (
+		out   bytes.Buffer
+		depth int
+		root  bool
+	)
+
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !root && t.Name.Local != "svg" {
+				return nil, ErrInvalidSVG
+			}
+
+			root = true
+			depth++
+
+			writeSVGStartElement(&out, t)
+		case xml.EndElement:
+			depth--
+
+			out.WriteString("</" + svgName(t.Name) + ">")
+		case xml.CharData:
+			if text := strings.Join(strings.Fields(string(t)), " "); text != "" && depth > 0 {
+				_ = xml.EscapeText(&out, []byte(text))
+			}
+		}
+	}
+
+	if !root || depth != 0 {
+		return nil, ErrInvalidSVG
+	}
+
+	return out.Bytes(), nil
+}
+
+// writeSVGStartElement writes the start tag with sorted attributes.
+func writeSVGStartElement(out *bytes.Buffer, t xml.StartElement) {
+	attrs := slices.Clone(t.Attr)
+	slices.SortFunc(attrs, func(a, b xml.Attr) int {
+		return strings.Compare(svgName(a.Name), svgName(b.Name))
+	})
+
+	out.WriteString("<" + svgName(t.Name))
+
+	for _, attr := range attrs {
+		out.WriteString(" " + svgName(attr.Name) + `="`)
+		_ = xml.EscapeText(out, []byte(strings.Join(strings.Fields(attr.Value), " ")))
+		out.WriteString(`"`)
+	}
+
+	out.WriteString(">")
+}
+
+// svgName returns the name with its namespace prefix.
+func svgName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// SVGHash returns the SHA-256 of the canonical form of an SVG document.
+func SVGHash(body []byte) (string, error) {
+	canonical, err := CanonicalSVG(body)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SVGMatcher identifies SVG favicons using the canonical hashes
+// of the database (entries with the "svg:" prefix).
+type SVGMatcher struct {
+	Database Database
+}
+
+// Name returns "svg".
+func (m *SVGMatcher) Name() string {
+	return SVGMatcherName
+}
+
+// Match looks up the canonical SVG hash in the database.
+func (m *SVGMatcher) Match(_ context.Context, favicon Favicon) (output.Signatures, error) {
+	if favicon.Hashes.SVG == "" {
+		return nil, nil
+	}
+
+	return m.Database[SVGHashPrefix+favicon.Hashes.SVG], nil
+}
@@ -8,19 +8,29 @@ package favirecon
 
 import (
 	"errors"
+	"fmt"
+	"net/netip"
 	"net/url"
 	"strings"
+
+	inputpkg "github.com/edoardottt/favirecon/pkg/input"
 )
 
 const (
-	MinURLLength = 4
+	MinURLLength         = 4
+	DefaultFaviconSource = "favicon.ico"
 )
 
 var (
-	ErrMalformedURL = errors.New("malformed input URL")
+	ErrMalformedURL   = errors.New("malformed input URL")
+	ErrUnknownProbing = errors.New("unknown probing strategy")
 )
 
 func resolveURL(baseURL, ref string) string {
+	if isDataURI(ref) {
+		return ref
+	}
+
 	base, err := url.Parse(baseURL)
 	if err != nil {
 		return ref // fallback
@@ -34,6 +44,71 @@ func resolveURL(baseURL, ref string) string {
 	return base.ResolveReference(u).String()
 }
 
+// ProbeURLs returns the URLs to be probed for the input.
+// If the input has no scheme, the probing strategy decides which
+// schemes are tried and in which order (see input.Probe* values).
+func ProbeURLs(input, strategy string) ([]string, error) {
+	if len(input) < MinURLLength {
+		return nil, ErrMalformedURL
+	}
+
+	if strings.Contains(input, "://") {
+		return []string{input}, nil
+	}
+
+	var schemes []string
+
+	switch strategy {
+	case inputpkg.ProbeHTTP:
+		schemes = []string{"http"}
+	case inputpkg.ProbeHTTPS:
+		schemes = []string{"https"}
+	case inputpkg.ProbeHTTPSHTTP, inputpkg.ProbeBoth:
+		schemes = []string{"https", "http"}
+	case inputpkg.ProbeHTTPHTTPS:
+		schemes = []string{"http", "https"}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProbing, strategy)
+	}
+
+	input = bracketIPv6(input)
+
+	urls := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		urls = append(urls, scheme+"://"+input)
+	}
+
+	return urls, nil
+}
+
+// bracketIPv6 encloses in brackets the host of a target without scheme
+// if it is a bare IPv6 address (e.g. 2001:db8::1/path becomes
+// [2001:db8::1]/path), so that it can be used as URL host.
+// A zone identifier is escaped as required by RFC 6874.
+func bracketIPv6(input string) string {
+	host, path := input, ""
+	if i := strings.Index(input, "/"); i != -1 {
+		host, path = input[:i], input[i:]
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !addr.Is6() {
+		return input
+	}
+
+	return "[" + strings.Replace(host, "%", "%25", 1) + "]" + path
+}
+
+// urlScheme returns the scheme of the URL.
+func urlScheme(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Scheme
+}
+
 // PrepareURL takes as input a string and prepares
 // the input URL in order to get the favicon icon.
 func PrepareURL(input string) (string, error) {
@@ -42,7 +117,7 @@ func PrepareURL(input string) (string, error) {
 	}
 
 	if !strings.Contains(input, "://") {
-		input = "http://" + input
+		input = "http://" + bracketIPv6(input)
 	}
 
 	u, err := url.Parse(input)
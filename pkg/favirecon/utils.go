@@ -8,20 +8,103 @@ package favirecon
 
 import (
 	"bytes"
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"net"
 
-	"github.com/projectdiscovery/mapcidr"
+	"github.com/edoardottt/favirecon/pkg/input"
 	"github.com/twmb/murmur3"
 )
 
 var (
-	ErrCidrBadFormat = errors.New("malformed input CIDR")
-	ErrEmptyBody     = errors.New("empty body")
+	ErrEmptyBody = errors.New("empty body")
 )
 
+// FaviconHashes contains all the hashes computed for a favicon.
+// MMH3 is the Shodan-style hash (also used by FOFA and ZoomEye),
+// MD5 is used by Censys and ZoomEye, SHA256 is useful for internal
+// asset inventories. DHash is the perceptual hash (see PerceptualHash),
+// empty if the image can't be decoded. Frames contains the hashes of
+// the images embedded in ICO files. SVG is the hash of the canonical
+// form of SVG favicons (see SVGHash).
+type FaviconHashes struct {
+	MMH3   string
+	MD5    string
+	SHA256 string
+	DHash  string
+	Frames []FrameHashes
+	SVG    string
+}
+
+// Values returns the non-empty exact hashes, murmur3 first.
+func (h FaviconHashes) Values() []string {
+	values := []string{}
+
+	for _, v := range []string{h.MMH3, h.MD5, h.SHA256} {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// Favicon is a favicon retrieved from a target.
+// MediaType is set for favicons embedded in data: URIs.
+// Err is set if the favicon has been rejected (too large or invalid).
+type Favicon struct {
+	URL       string
+	Source    string
+	MediaType string
+	// Redirects is the redirect chain followed to get the favicon,
+	// from URL to the final URL (see Response.Redirects).
+	Redirects []string
+	Hashes    FaviconHashes
+	Err       error
+}
+
+// isRejected checks if err is a favicon rejection, i.e. the
+// favicon exists but it's too large or it's not a valid image.
+func isRejected(err error) bool {
+	return errors.Is(err, ErrFaviconTooLarge) || errors.Is(err, ErrInvalidFavicon)
+}
+
+// isNotFound checks if err means that the target has no favicon.
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrFaviconNotFound) ||
+		errors.Is(err, ErrEmptyBody) ||
+		errors.Is(err, ErrFaviconLinkTagNotFound) ||
+		errors.Is(err, ErrHTMLNotFetched)
+}
+
+// rejectionCategory returns a short description of the rejection reason.
+func rejectionCategory(err error) string {
+	switch {
+	case errors.Is(err, ErrFaviconTooLarge):
+		return ErrFaviconTooLarge.Error()
+	case errors.Is(err, ErrInvalidFavicon):
+		return ErrInvalidFavicon.Error()
+	default:
+		return err.Error()
+	}
+}
+
+// containsFavicon checks if a favicon with the same URL
+// has already been collected (and the same hash, data: URIs
+// with the same identifier can differ).
+func containsFavicon(favicons []Favicon, favicon Favicon) bool {
+	for _, f := range favicons {
+		if f.URL == favicon.URL && f.Hashes.SHA256 == favicon.Hashes.SHA256 {
+			return true
+		}
+	}
+
+	return false
+}
+
 func contains(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {
@@ -57,21 +140,76 @@ func GetFaviconHash(input []byte) string {
 	return fmt.Sprint(int32(murmur3.Sum32(b64)))
 }
 
-func handleCidrInput(inputCidr string) ([]string, error) {
-	if !isCidr(inputCidr) {
-		return nil, ErrCidrBadFormat
+// GetFaviconHashes computes all the supported hashes of a favicon.
+func GetFaviconHashes(input []byte) FaviconHashes {
+	return faviconHashes(input, true)
+}
+
+// faviconHashes computes the hashes of a favicon, the perceptual
+// hashes (decoding the image) only if perceptual is true.
+func faviconHashes(input []byte, perceptual bool) FaviconHashes {
+	return imageHashes(contentHashes(input), input, perceptual)
+}
+
+// perceptualHashing checks if the perceptual hashes have to be computed,
+// i.e. if perceptual matching is enabled (see NewScanner).
+func perceptualHashing(options *input.Options) bool {
+	return options.PHashDistance >= 0
+}
+
+// contentHashes computes the hashes of the raw content
+// (murmur3, MD5 and SHA-256).
+func contentHashes(input []byte) FaviconHashes {
+	md5Sum := md5.Sum(input) //nolint:gosec
+	sha256Sum := sha256.Sum256(input)
+
+	return FaviconHashes{
+		MMH3:   GetFaviconHash(input),
+		MD5:    hex.EncodeToString(md5Sum[:]),
+		SHA256: hex.EncodeToString(sha256Sum[:]),
+	}
+}
+
+// imageHashes adds to the hashes of a favicon the ones computed
+// parsing the image: the ICO frames hashes, the canonical SVG hash
+// and, if perceptual is true, the perceptual hash.
+func imageHashes(hashes FaviconHashes, body []byte, perceptual bool) FaviconHashes {
+	switch SniffImageType(body) {
+	case FormatSVG:
+		hashes.SVG, _ = SVGHash(body)
+
+		return hashes
+	case FormatICO:
+		hashes.Frames = icoFrameHashes(body, perceptual)
 	}
 
-	ips, err := mapcidr.IPAddresses(inputCidr)
-	if err != nil {
-		return nil, err
+	if perceptual {
+		// Not all the formats can be decoded (e.g. WebP).
+		hashes.DHash, _ = PerceptualHash(body)
 	}
 
-	return ips, nil
+	return hashes
 }
 
-// isCidr determines if the given ip is a cidr range.
-func isCidr(inputCidr string) bool {
-	_, _, err := net.ParseCIDR(inputCidr)
-	return err == nil
+// matchHashes checks if at least one of the favicon hashes
+// is contained in the hashes provided by the user.
+// ICO frames hashes are checked too.
+func matchHashes(filter []string, hashes FaviconHashes) bool {
+	for _, v := range hashes.Values() {
+		if contains(filter, v) {
+			return true
+		}
+	}
+
+	if hashes.SVG != "" && contains(filter, hashes.SVG) {
+		return true
+	}
+
+	for _, frame := range hashes.Frames {
+		if matchHashes(filter, frame.Hashes) {
+			return true
+		}
+	}
+
+	return false
 }
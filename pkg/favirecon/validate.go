@@ -0,0 +1,121 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package favirecon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+const (
+	FormatICO  = "ico"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatJPEG = "jpeg"
+	FormatBMP  = "bmp"
+	FormatWebP = "webp"
+	FormatSVG  = "svg"
+
+	sniffLength = 512
+)
+
+var (
+	ErrInvalidFavicon = errors.New("invalid favicon")
+)
+
+//nolint:gochecknoglobals
+var (
+	magicICO  = []byte{0x00, 0x00, 0x01, 0x00}
+	magicCUR  = []byte{0x00, 0x00, 0x02, 0x00}
+	magicPNG  = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+	magicJPEG = []byte{0xff, 0xd8, 0xff}
+	utf8BOM   = []byte{0xef, 0xbb, 0xbf}
+
+	// genericContentTypes are not image types, but are commonly
+	// (and legitimately) used to serve favicons.
+	genericContentTypes = []string{
+		"application/octet-stream",
+		"binary/octet-stream",
+		"application/x-icon",
+		"application/ico",
+		"application/x-ico",
+		"application/xml",
+		"text/xml",
+		"text/plain",
+	}
+)
+
+// SniffImageType detects the image format using the magic bytes.
+// It returns an empty string if the format is not recognised.
+func SniffImageType(body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, magicICO), bytes.HasPrefix(body, magicCUR):
+		return FormatICO
+	case bytes.HasPrefix(body, magicPNG):
+		return FormatPNG
+	case bytes.HasPrefix(body, []byte("GIF87a")), bytes.HasPrefix(body, []byte("GIF89a")):
+		return FormatGIF
+	case bytes.HasPrefix(body, magicJPEG):
+		return FormatJPEG
+	case len(body) >= 12 && bytes.Equal(body[:4], []byte("RIFF")) && bytes.Equal(body[8:12], []byte("WEBP")):
+		return FormatWebP
+	case bytes.HasPrefix(body, []byte("BM")):
+		return FormatBMP
+	case isSVG(body):
+		return FormatSVG
+	}
+
+	return ""
+}
+
+// isSVG checks if the body looks like an SVG document.
+func isSVG(body []byte) bool {
+	head := body
+	if len(head) > sniffLength {
+		head = head[:sniffLength]
+	}
+
+	head = bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(head, utf8BOM)))
+	if bytes.HasPrefix(head, []byte("<svg")) {
+		return true
+	}
+
+	return (bytes.HasPrefix(head, []byte("<?xml")) ||
+		bytes.HasPrefix(head, []byte("<!--")) ||
+		bytes.HasPrefix(head, []byte("<!doctype svg"))) &&
+		bytes.Contains(head, []byte("<svg"))
+}
+
+// ValidateFavicon checks that the Content-Type is an image (or a generic
+// type commonly used for favicons) and that the body is a known image
+// format (ICO, PNG, GIF, JPEG, BMP, WebP or SVG).
+// The returned error contains the rejection reason.
+func ValidateFavicon(contentType string, body []byte) error {
+	if len(body) == 0 {
+		return ErrEmptyBody
+	}
+
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("%w: malformed Content-Type %q", ErrInvalidFavicon, contentType)
+		}
+
+		if !strings.HasPrefix(mediaType, "image/") && !contains(genericContentTypes, mediaType) {
+			return fmt.Errorf("%w: Content-Type %s is not an image", ErrInvalidFavicon, mediaType)
+		}
+	}
+
+	if SniffImageType(body) == "" {
+		return fmt.Errorf("%w: unrecognised image format", ErrInvalidFavicon)
+	}
+
+	return nil
+}
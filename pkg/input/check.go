@@ -18,6 +18,8 @@ var (
 	ErrMutexFlags    = errors.New("incompatible flags specified")
 	ErrNoInput       = errors.New("no input specified")
 	ErrNegativeValue = errors.New("must be positive")
+	ErrNoDatabase    = errors.New("no signature database specified")
+	ErrInvalidValue  = errors.New("invalid value")
 )
 
 func (options *Options) validateOptions() error {
@@ -25,7 +27,11 @@ func (options *Options) validateOptions() error {
 		return fmt.Errorf("%w: %s and %s", ErrMutexFlags, "silent", "verbose")
 	}
 
-	if options.Input == "" && options.FileInput == "" && !fileutil.HasStdin() {
+	if len(options.Files) != 0 && (options.Input != "" || options.FileInput != "") {
+		return fmt.Errorf("%w: %s and %s/%s", ErrMutexFlags, "file", "url", "list")
+	}
+
+	if options.Input == "" && options.FileInput == "" && len(options.Files) == 0 && !fileutil.HasStdin() {
 		return fmt.Errorf("%w", ErrNoInput)
 	}
 
@@ -33,10 +39,32 @@ func (options *Options) validateOptions() error {
 		return fmt.Errorf("concurrency: %w", ErrNegativeValue)
 	}
 
+	switch options.Probe {
+	case ProbeHTTP, ProbeHTTPS, ProbeHTTPSHTTP, ProbeHTTPHTTPS, ProbeBoth:
+	default:
+		return fmt.Errorf("probe: %w %s", ErrInvalidValue, options.Probe)
+	}
+
+	if options.MaxSize <= 0 {
+		return fmt.Errorf("max size: %w", ErrNegativeValue)
+	}
+
+	if options.MaxRedirects < 0 {
+		return fmt.Errorf("max redirects: %w", ErrNegativeValue)
+	}
+
+	if options.PHashDistance < -1 || options.PHashDistance > MaxPHashDistance {
+		return fmt.Errorf("phash distance: %w %d", ErrInvalidValue, options.PHashDistance)
+	}
+
 	if options.RateLimit != 0 && options.RateLimit <= 0 {
 		return fmt.Errorf("rate limit: %w", ErrNegativeValue)
 	}
 
+	if options.NoDefaultDB && len(options.Databases) == 0 {
+		return fmt.Errorf("%w: %s requires %s", ErrNoDatabase, "no-default-db", "db")
+	}
+
 	if options.Proxy != "" && !checkProxy(options.Proxy) {
 		_, err := url.Parse(options.Proxy)
 		return fmt.Errorf("proxy URL: %w", err)
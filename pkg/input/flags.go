@@ -21,6 +21,23 @@ const (
 	DefaultTimeout     = 10
 	DefaultConcurrency = 50
 	DefaultRateLimit   = 0
+	DefaultMaxSize     = 1024
+	DefaultProbe       = ProbeHTTPSHTTP
+	// DefaultPHashDistance is the default maximum perceptual hash distance.
+	DefaultPHashDistance = 4
+	// MaxPHashDistance is the maximum perceptual hash distance (64 bits hashes).
+	MaxPHashDistance = 64
+	// DefaultMaxRedirects is the default maximum number of HTTP redirects followed.
+	DefaultMaxRedirects = 10
+)
+
+// Probing strategies for inputs without scheme.
+const (
+	ProbeHTTP      = "http"
+	ProbeHTTPS     = "https"
+	ProbeHTTPSHTTP = "https-http"
+	ProbeHTTPHTTPS = "http-https"
+	ProbeBoth      = "both"
 )
 
 // Options struct specifies how the tool
@@ -39,6 +56,52 @@ type Options struct {
 	RateLimit   int
 	Proxy       string
 	JSON        bool
+	All         bool
+	Databases   goflags.StringSlice
+	NoDefaultDB bool
+	Files       goflags.StringSlice
+	AllIcons    bool
+	Relaxed     bool
+	MaxSize     int
+	Probe       string
+	Ports       string
+	Shuffle     bool
+	Resume      string
+	// PHashDistance is the maximum perceptual hash distance
+	// of similar favicons, -1 disables perceptual matching.
+	PHashDistance int
+	// NoRedirects disables HTTP redirects.
+	NoRedirects bool
+	// MaxRedirects is the maximum number of HTTP redirects followed.
+	MaxRedirects int
+	// SameHostRedirects restricts redirects to the host of the requested
+	// URL (e.g. the icon URL, that can differ from the target host).
+	SameHostRedirects bool
+}
+
+// ApplyDefaults sets the default value of the options that can't be
+// zero, left unset by library callers building Options by hand.
+// MaxRedirects is set only if redirects are not disabled.
+func (options *Options) ApplyDefaults() {
+	if options.Concurrency == 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	if options.MaxSize == 0 {
+		options.MaxSize = DefaultMaxSize
+	}
+
+	if options.Probe == "" {
+		options.Probe = DefaultProbe
+	}
+
+	if options.MaxRedirects == 0 && !options.NoRedirects {
+		options.MaxRedirects = DefaultMaxRedirects
+	}
 }
 
 // configureOutput configures the output on the screen.
@@ -62,14 +125,29 @@ func ParseOptions() *Options {
 		flagSet.StringVarP(&options.Input, "url", "u", "", `Input domain`),
 		flagSet.StringVarP(&options.FileInput, "list", "l", "", `File containing input domains`),
 		flagSet.BoolVar(&options.Cidr, "cidr", false, `Interpret input as CIDR`),
+		flagSet.BoolVarP(&options.Shuffle, "shuffle", "sh", false, `Scan CIDR ranges in random order`),
+		flagSet.StringVarP(&options.Ports, "ports", "p", "", `Ports to scan for each host, lists, ranges and presets (e.g. 80,8000-8100,top-web)`),
+		flagSet.StringSliceVarP(&options.Files, "file", "f", nil, `Hash local favicon files or directories (no network traffic)`, goflags.CommaSeparatedStringSliceOptions),
+		flagSet.StringVarP(&options.Resume, "resume", "r", "", `Resume file recording completed targets, skipped when the scan is run again`),
 	)
 
 	flagSet.CreateGroup("configs", "Configurations",
-		flagSet.StringSliceVarP(&options.Hash, "hash", "", nil, `Filter results having these favicon hashes (comma separated)`, goflags.CommaSeparatedStringSliceOptions),
+		flagSet.StringSliceVarP(&options.Hash, "hash", "", nil, `Filter results having these favicon hashes, mmh3/MD5/SHA-256 (comma separated)`, goflags.CommaSeparatedStringSliceOptions),
 		flagSet.IntVarP(&options.Concurrency, "concurrency", "c", DefaultConcurrency, `Concurrency level`),
 		flagSet.IntVarP(&options.Timeout, "timeout", "t", DefaultTimeout, `Connection timeout in seconds`),
 		flagSet.IntVarP(&options.RateLimit, "rate-limit", "rl", DefaultRateLimit, `Set a rate limit (per second)`),
 		flagSet.StringVarP(&options.Proxy, "proxy", "px", "", `Set a proxy server (URL)`),
+		flagSet.BoolVarP(&options.All, "all", "a", false, `Report also favicons not found in the database`),
+		flagSet.BoolVarP(&options.AllIcons, "all-icons", "ai", false, `Hash every icon advertised by the page, not just the first one`),
+		flagSet.StringVarP(&options.Probe, "probe", "pr", DefaultProbe, `Schemes to try for inputs without scheme (http, https, https-http, http-https, both)`),
+		flagSet.BoolVarP(&options.Relaxed, "relaxed", "rx", false, `Accept favicons without checking Content-Type and image format`),
+		flagSet.IntVarP(&options.MaxSize, "max-size", "ms", DefaultMaxSize, `Maximum favicon size in KB`),
+		flagSet.BoolVarP(&options.NoRedirects, "no-redirects", "nr", false, `Do not follow redirects`),
+		flagSet.IntVarP(&options.MaxRedirects, "max-redirects", "mr", DefaultMaxRedirects, `Maximum number of redirects to follow`),
+		flagSet.BoolVarP(&options.SameHostRedirects, "same-host-redirects", "shr", false, `Follow only redirects to the host of the requested URL`),
+		flagSet.IntVarP(&options.PHashDistance, "phash-distance", "pd", DefaultPHashDistance, `Maximum perceptual hash distance (0-64) to report similar favicons, -1 to disable`),
+		flagSet.StringSliceVarP(&options.Databases, "db", "", nil, `Additional signature database files, JSON or YAML (overriding the default one)`, goflags.CommaSeparatedStringSliceOptions),
+		flagSet.BoolVarP(&options.NoDefaultDB, "no-default-db", "ndb", false, `Do not load the default signature database`),
 	)
 
 	// Output
@@ -99,6 +177,11 @@ func ParseOptions() *Options {
 
 	output.ShowBanner()
 
+	// -max-redirects 0 is the same as -no-redirects.
+	if options.MaxRedirects == 0 {
+		options.NoRedirects = true
+	}
+
 	return options
 }
 
@@ -7,15 +7,65 @@ This repository is under MIT License https://github.com/edoardottt/favirecon/blo
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 )
 
+const (
+	// UnknownName is the name of the favicons not found in the database.
+	UnknownName = "unknown"
+)
+
 type Found struct {
-	URL  string `json:"URL,omitempty"`
-	Hash string `json:"Hash,omitempty"`
-	Name string `json:"Name,omitempty"`
+	URL           string      `json:"URL,omitempty"`
+	Hash          string      `json:"Hash,omitempty"`
+	Name          string      `json:"Name,omitempty"`
+	FaviconURL    string      `json:"FaviconURL,omitempty"`
+	FinalURL      string      `json:"FinalURL,omitempty"`
+	RedirectChain []string    `json:"RedirectChain,omitempty"`
+	MediaType     string      `json:"MediaType,omitempty"`
+	MD5           string      `json:"MD5,omitempty"`
+	SHA256        string      `json:"SHA256,omitempty"`
+	Product       string      `json:"Product,omitempty"`
+	Vendor        string      `json:"Vendor,omitempty"`
+	Category      string      `json:"Category,omitempty"`
+	Tags          []string    `json:"Tags,omitempty"`
+	CPE           string      `json:"CPE,omitempty"`
+	Reference     string      `json:"Reference,omitempty"`
+	Candidates    []Signature `json:"Candidates,omitempty"`
+	Source        string      `json:"Source,omitempty"`
+	Scheme        string      `json:"Scheme,omitempty"`
+	Matcher       string      `json:"Matcher,omitempty"`
+	PHash         string      `json:"PHash,omitempty"`
+	SVG           string      `json:"SVG,omitempty"`
+	Distance      *int        `json:"Distance,omitempty"`
+	Frame         string      `json:"Frame,omitempty"`
+	Frames        []Frame     `json:"Frames,omitempty"`
+	Error         string      `json:"Error,omitempty"`
+	Reason        string      `json:"Reason,omitempty"`
+}
+
+// Signature describes the product identified by a favicon hash.
+type Signature struct {
+	Name      string   `json:"Name,omitempty"`
+	Product   string   `json:"Product,omitempty"`
+	Vendor    string   `json:"Vendor,omitempty"`
+	Category  string   `json:"Category,omitempty"`
+	Tags      []string `json:"Tags,omitempty"`
+	CPE       string   `json:"CPE,omitempty"`
+	Reference string   `json:"Reference,omitempty"`
+}
+
+// Frame contains the hashes of an image embedded in an ICO file.
+type Frame struct {
+	Frame  string `json:"Frame,omitempty"`
+	Hash   string `json:"Hash,omitempty"`
+	MD5    string `json:"MD5,omitempty"`
+	SHA256 string `json:"SHA256,omitempty"`
+	PHash  string `json:"PHash,omitempty"`
 }
 
 type Result struct {
@@ -49,9 +99,140 @@ func (o *Result) Printed(found string) bool {
 	return true
 }
 
+// UnmarshalJSON accepts both the legacy format (a plain string
+// containing the name) and the structured one.
+func (s *Signature) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*s = Signature{Name: name}
+
+		return nil
+	}
+
+	type signature Signature
+
+	var sig signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return err
+	}
+
+	*s = Signature(sig)
+
+	if s.Name == "" {
+		s.Name = s.Product
+	}
+
+	return nil
+}
+
+// Signatures contains all the products sharing the same favicon hash.
+type Signatures []Signature
+
+// UnmarshalJSON accepts both a single signature and a list of signatures.
+func (s *Signatures) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var signatures []Signature
+		if err := json.Unmarshal(data, &signatures); err != nil {
+			return err
+		}
+
+		*s = signatures
+
+		return nil
+	}
+
+	var signature Signature
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return err
+	}
+
+	*s = Signatures{signature}
+
+	return nil
+}
+
+// Names returns the names of all the signatures.
+func (s Signatures) Names() []string {
+	names := make([]string, 0, len(s))
+	for _, signature := range s {
+		names = append(names, signature.Name)
+	}
+
+	return names
+}
+
+// SetSignatures fills the product details using the signatures.
+// If more than one product shares the same favicon, the name contains
+// all the candidates and the details are reported in Candidates.
+func (f *Found) SetSignatures(s Signatures) {
+	switch len(s) {
+	case 0:
+		return
+	case 1:
+		f.SetSignature(s[0])
+	default:
+		f.Name = strings.Join(s.Names(), ", ")
+		f.Candidates = s
+	}
+}
+
+// SetSignature fills the product details using the signature.
+func (f *Found) SetSignature(s Signature) {
+	f.Name = s.Name
+	f.Product = s.Product
+	f.Vendor = s.Vendor
+	f.Category = s.Category
+	f.Tags = s.Tags
+	f.CPE = s.CPE
+	f.Reference = s.Reference
+}
+
+// SetRedirects fills the URL the favicon was served from (after
+// redirects) and the redirect chain, from FaviconURL to FinalURL.
+// chain is empty if the favicon was not redirected.
+func (f *Found) SetRedirects(chain []string) {
+	if len(chain) == 0 {
+		return
+	}
+
+	f.FinalURL = chain[len(chain)-1]
+	f.RedirectChain = chain
+}
+
 // Format returns a string ready to be printed.
 func (f *Found) Format() string {
-	return fmt.Sprintf("[%s] [%s] %s", f.Hash, f.Name, f.URL)
+	name := f.Name
+	if f.Distance != nil {
+		name = fmt.Sprintf("probably %s (distance %d)", f.Name, *f.Distance)
+	}
+
+	out := fmt.Sprintf("[%s] [%s] %s", f.Hash, name, f.URL)
+
+	// Unknown favicons can be looked up elsewhere.
+	if f.Name == UnknownName && f.FaviconURL != "" {
+		out += fmt.Sprintf(" [favicon %s]", f.FaviconURL)
+	}
+
+	if f.Source != "" {
+		out += fmt.Sprintf(" [%s]", f.Source)
+	}
+
+	if f.Frame != "" {
+		out += fmt.Sprintf(" [frame %s]", f.Frame)
+	}
+
+	if f.FinalURL != "" {
+		out += fmt.Sprintf(" [redirected to %s]", f.FinalURL)
+	}
+
+	switch {
+	case f.Reason != "":
+		out += fmt.Sprintf(" (%s)", f.Reason)
+	case f.Error != "":
+		out += fmt.Sprintf(" (%s)", f.Error)
+	}
+
+	return out
 }
 
 // FormatJSON returns the input as JSON string.
@@ -0,0 +1,97 @@
+/*
+favirecon - Use favicon.ico to improve your target recon phase. Quickly detect technologies, WAF, exposed panels, known services.
+
+This repository is under MIT License https://github.com/edoardottt/favirecon/blob/main/LICENSE
+*/
+
+package output_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/edoardottt/favirecon/pkg/output"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignaturesUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  output.Signatures
+	}{
+		{
+			name:  "legacy name",
+			input: `"pfSense"`,
+			want:  output.Signatures{{Name: "pfSense"}},
+		},
+		{
+			name:  "structured signature without name",
+			input: `{"product": "pfSense", "vendor": "Netgate"}`,
+			want:  output.Signatures{{Name: "pfSense", Product: "pfSense", Vendor: "Netgate"}},
+		},
+		{
+			name:  "multiple candidates",
+			input: `["Spring Boot", {"name": "Appliance", "category": "panel"}]`,
+			want:  output.Signatures{{Name: "Spring Boot"}, {Name: "Appliance", Category: "panel"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got output.Signatures
+
+			err := json.Unmarshal([]byte(tt.input), &got)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFoundFormat(t *testing.T) {
+	distance := 3
+
+	tests := []struct {
+		name  string
+		found output.Found
+		want  string
+	}{
+		{
+			name:  "known",
+			found: output.Found{Hash: "116323821", Name: "Spring Boot", URL: "https://example.com", FaviconURL: "https://example.com/favicon.ico"},
+			want:  "[116323821] [Spring Boot] https://example.com",
+		},
+		{
+			name:  "unknown",
+			found: output.Found{Hash: "-1541278541", Name: output.UnknownName, URL: "https://example.com", FaviconURL: "https://cdn.example.com/icon.png"},
+			want:  "[-1541278541] [unknown] https://example.com [favicon https://cdn.example.com/icon.png]",
+		},
+		{
+			name:  "unknown local file",
+			found: output.Found{Hash: "-1541278541", Name: output.UnknownName, URL: "favicon.ico"},
+			want:  "[-1541278541] [unknown] favicon.ico",
+		},
+		{
+			name: "rejected",
+			found: output.Found{Name: output.UnknownName, URL: "https://example.com", FaviconURL: "https://example.com/favicon.ico",
+				Error: "favicon too large"},
+			want: "[] [unknown] https://example.com [favicon https://example.com/favicon.ico] (favicon too large)",
+		},
+		{
+			name: "rejected with reason",
+			found: output.Found{Name: output.UnknownName, URL: "https://example.com", FaviconURL: "https://example.com/favicon.ico",
+				Error: "invalid favicon", Reason: "invalid favicon: Content-Type text/html is not an image"},
+			want: "[] [unknown] https://example.com [favicon https://example.com/favicon.ico] " +
+				"(invalid favicon: Content-Type text/html is not an image)",
+		},
+		{
+			name:  "similar",
+			found: output.Found{Hash: "1", Name: "Example", URL: "https://example.com", Distance: &distance, Source: "icon", Frame: "32x32 #2"},
+			want:  "[1] [probably Example (distance 3)] https://example.com [icon] [frame 32x32 #2]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.found.Format())
+		})
+	}
+}